@@ -2,10 +2,11 @@
 package cyclops
 
 import (
-	"fmt"
-	"github.com/flannel-dev-lab/cyclops/v2/input"
+	"github.com/flannel-dev-lab/cyclops/v2/router"
+	"github.com/google/uuid"
 	"log"
 	"net/http"
+	"strconv"
 )
 
 const banner = `
@@ -17,26 +18,34 @@ const banner = `
 https://github.com/flannel-dev-lab/cyclops
 `
 
-// StartServer starts a simple http server
+// StartServer starts a simple http server, which shuts down gracefully on SIGINT and SIGTERM
 func StartServer(address string, handler http.Handler) {
-	fmt.Print(banner)
-	if err := http.ListenAndServe(address, handler); err != http.ErrServerClosed {
+	if err := NewServer(address, handler).Start(); err != nil {
 		// Error starting or closing listener:
 		log.Fatalf("HTTP server ListenAndServe: %v", err)
 	}
 }
 
-// StartTLSServer starts a TLS server with provided TLS cert and key files
+// StartTLSServer starts a TLS server with provided TLS cert and key files, which shuts down gracefully on SIGINT and
+// SIGTERM
 func StartTLSServer(address string, handler http.Handler, certFile, keyFile string) {
-	fmt.Print(banner)
-
-	if err := http.ListenAndServeTLS(address, certFile, keyFile, handler); err != http.ErrServerClosed {
+	if err := NewServer(address, handler).StartTLS(certFile, keyFile); err != nil {
 		// Error starting or closing listener:
 		log.Fatalf("HTTPS server ListenAndServe: %v", err)
 	}
 }
 
-// Param - Get a url parameter by name
+// Param - Get a named or catch-all url parameter of the matched route by name
 func Param(r *http.Request, name string) string {
-	return input.Query(name, r)
+	return router.ParamsFromContext(r.Context()).Get(name)
+}
+
+// ParamInt - Get a url parameter by name as an int, an error is returned when it is not a valid integer
+func ParamInt(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(Param(r, name))
+}
+
+// ParamUUID - Get a url parameter by name as a UUID, an error is returned when it is not a valid UUID
+func ParamUUID(r *http.Request, name string) (uuid.UUID, error) {
+	return uuid.Parse(Param(r, name))
 }
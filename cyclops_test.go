@@ -0,0 +1,71 @@
+package cyclops
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flannel-dev-lab/cyclops/v2/router"
+	"github.com/google/uuid"
+)
+
+func TestParam(t *testing.T) {
+	var id, query string
+
+	r := router.New(false, nil, nil)
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		id = Param(r, "id")
+		query = Param(r, "q")
+	})
+
+	req, _ := http.NewRequest("GET", "/users/1?q=cyclops", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if id != "1" {
+		t.Errorf("%s: expected '1' got '%s'", t.Name(), id)
+	}
+
+	if query != "" {
+		t.Errorf("%s: query parameters should not be returned as params, got '%s'", t.Name(), query)
+	}
+}
+
+func TestParamTyped(t *testing.T) {
+	var id int
+	var key uuid.UUID
+	var idErr, keyErr error
+
+	r := router.New(false, nil, nil)
+	r.Get("/users/:id/keys/:key", func(w http.ResponseWriter, r *http.Request) {
+		id, idErr = ParamInt(r, "id")
+		key, keyErr = ParamUUID(r, "key")
+	})
+
+	cases := []struct {
+		Path     string
+		ID       int
+		IDErr    bool
+		Key      string
+		KeyError bool
+	}{
+		{"/users/42/keys/6ba7b810-9dad-11d1-80b4-00c04fd430c8", 42, false, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", false},
+		{"/users/abc/keys/xyz", 0, true, "00000000-0000-0000-0000-000000000000", true},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest("GET", testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if id != testCase.ID || (idErr != nil) != testCase.IDErr {
+			t.Errorf("%s: expected %d got %d (%v) for %s", t.Name(), testCase.ID, id, idErr, testCase.Path)
+		}
+
+		if key.String() != testCase.Key || (keyErr != nil) != testCase.KeyError {
+			t.Errorf("%s: expected %s got %s (%v) for %s", t.Name(), testCase.Key, key, keyErr, testCase.Path)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/flannel-dev-lab/cyclops/v2
 
-go 1.19
+go 1.24
 
 require (
 	github.com/gomodule/redigo v1.8.9
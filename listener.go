@@ -0,0 +1,61 @@
+package cyclops
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// ListenFunc creates the listener a Server accepts connections on
+type ListenFunc func() (net.Listener, error)
+
+// systemdFirstFD is the first file descriptor systemd passes to an activated service
+const systemdFirstFD = 3
+
+// TCPSocket returns a ListenFunc listening on the TCP address, like :8080
+func TCPSocket(address string) ListenFunc {
+	return func() (net.Listener, error) {
+		return net.Listen("tcp", address)
+	}
+}
+
+// UnixSocket returns a ListenFunc listening on the Unix socket at path. A socket left at path by a previous run is
+// removed and the socket is removed again when the listener is closed
+func UnixSocket(path string) ListenFunc {
+	return func() (net.Listener, error) {
+		if stat, err := os.Stat(path); err == nil && stat.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+
+		return net.Listen("unix", path)
+	}
+}
+
+// SystemdSocket returns a ListenFunc using the socket systemd passed to the process with socket activation, index is
+// the position of the socket among the ListenStream entries of the socket unit starting at 0
+func SystemdSocket(index int) ListenFunc {
+	return func() (net.Listener, error) {
+		pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+		if err != nil || pid != os.Getpid() {
+			return nil, errors.New("cyclops: no sockets were passed by systemd")
+		}
+
+		count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+		if err != nil {
+			return nil, fmt.Errorf("cyclops: invalid LISTEN_FDS: %w", err)
+		}
+
+		if index < 0 || index >= count {
+			return nil, fmt.Errorf("cyclops: systemd passed %d sockets, there is no socket %d", count, index)
+		}
+
+		file := os.NewFile(uintptr(systemdFirstFD+index), "systemd-socket-"+strconv.Itoa(index))
+		defer file.Close()
+
+		return net.FileListener(file)
+	}
+}
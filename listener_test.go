@@ -0,0 +1,101 @@
+package cyclops
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestServer_H2CUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "cyclops.sock")
+
+	server := NewServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	}))
+	server.Options.HideBanner = true
+	server.Listen = UnixSocket(socket)
+	server.H2C = true
+
+	started := make(chan struct{})
+	server.OnStart(func() error {
+		close(started)
+		return nil
+	})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+	<-started
+
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+	}
+
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+
+	cases := []struct {
+		Transport *http.Transport
+		Expected  string
+	}{
+		{&http.Transport{DialContext: dial}, "HTTP/1.1"},
+		{&http.Transport{DialContext: dial, Protocols: h2c}, "HTTP/2.0"},
+	}
+
+	for _, testCase := range cases {
+		client := &http.Client{Transport: testCase.Transport}
+
+		resp, err := client.Get("http://cyclops/")
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		testCase.Transport.CloseIdleConnections()
+
+		if string(body) != testCase.Expected {
+			t.Errorf("%s: expected %q got %q", t.Name(), testCase.Expected, string(body))
+		}
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("%s: unexpected shutdown error: %s", t.Name(), err.Error())
+	}
+
+	if err := <-stopped; err != nil {
+		t.Errorf("%s: unexpected start error: %s", t.Name(), err.Error())
+	}
+}
+
+func TestSystemdSocket(t *testing.T) {
+	cases := []struct {
+		PID   string
+		FDs   string
+		Index int
+	}{
+		{"", "", 0},
+		{"1", "1", 0},
+		{"self", "1", 1},
+		{"self", "x", 0},
+	}
+
+	for _, testCase := range cases {
+		pid := testCase.PID
+		if pid == "self" {
+			pid = strconv.Itoa(os.Getpid())
+		}
+
+		t.Setenv("LISTEN_PID", pid)
+		t.Setenv("LISTEN_FDS", testCase.FDs)
+
+		if _, err := SystemdSocket(testCase.Index)(); err == nil {
+			t.Errorf("%s: %+v: expected an error", t.Name(), testCase)
+		}
+	}
+}
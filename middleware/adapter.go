@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// HandlerMiddleware is the form of middleware used by net/http and most third-party packages, any
+// func(http.Handler) http.Handler can be passed where a HandlerMiddleware is expected
+type HandlerMiddleware func(http.Handler) http.Handler
+
+// FromHandler adapts a HandlerMiddleware so it can be used in a Chain or with Router.Use
+func FromHandler(middleware HandlerMiddleware) Middlewares {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return middleware(next).ServeHTTP
+	}
+}
+
+// ToHandler adapts cyclops middleware so it can be used with plain net/http muxes and third-party packages
+func ToHandler(middleware Middlewares) HandlerMiddleware {
+	return func(next http.Handler) http.Handler {
+		return middleware(next.ServeHTTP)
+	}
+}
+
+// AppendHandler is like Append but takes HandlerMiddleware, so third-party middleware can be chained with cyclops
+// middleware
+func (chain *Chain) AppendHandler(middlewares ...HandlerMiddleware) *Chain {
+	return chain.Append(fromHandlers(middlewares)...)
+}
+
+// PrependHandler is like Prepend but takes HandlerMiddleware
+func (chain *Chain) PrependHandler(middlewares ...HandlerMiddleware) *Chain {
+	return chain.Prepend(fromHandlers(middlewares)...)
+}
+
+// ThenHandler is like Then but takes and returns an http.Handler
+func (chain *Chain) ThenHandler(handler http.Handler) http.Handler {
+	return chain.Then(handler.ServeHTTP)
+}
+
+// Handler returns the chain as a HandlerMiddleware, so it can wrap the handlers of a plain net/http mux
+func (chain *Chain) Handler() HandlerMiddleware {
+	return func(next http.Handler) http.Handler {
+		return chain.ThenHandler(next)
+	}
+}
+
+// fromHandlers adapts every HandlerMiddleware with FromHandler
+func fromHandlers(middlewares []HandlerMiddleware) []Middlewares {
+	adapted := make([]Middlewares, len(middlewares))
+	for idx, middleware := range middlewares {
+		adapted[idx] = FromHandler(middleware)
+	}
+
+	return adapted
+}
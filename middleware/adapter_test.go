@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
+)
+
+// traceHandler returns a net/http style middleware recording its name in calls before calling the next handler
+func traceHandler(name string, calls *[]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChain_HandlerMiddleware(t *testing.T) {
+	var calls []string
+
+	chain := middleware.NewPlainChain(trace("cyclops", &calls)).
+		AppendHandler(traceHandler("appended", &calls)).
+		PrependHandler(traceHandler("prepended", &calls))
+
+	handler := chain.ThenHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	expected := []string{"appended", "cyclops", "prepended", "handler"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("%s: expected %v got %v", t.Name(), expected, calls)
+	}
+}
+
+func TestAdapters(t *testing.T) {
+	var calls []string
+
+	// A cyclops middleware adapted to net/http and back behaves like the original
+	roundTrip := middleware.FromHandler(middleware.ToHandler(trace("cyclops", &calls)))
+
+	mux := http.NewServeMux()
+	mux.Handle("/", middleware.NewPlainChain(roundTrip).Handler()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("%s: expected %d got %d", t.Name(), http.StatusAccepted, w.Code)
+	}
+
+	expected := []string{"cyclops", "handler"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("%s: expected %v got %v", t.Name(), expected, calls)
+	}
+}
@@ -8,16 +8,28 @@ import (
 
 // CORS Contains all the CORS configurations
 type CORS struct {
-	// AllowedOrigin Specifies which origin should be allowed, if you want to allow all use *
+	// AllowedOrigin Specifies which origin should be allowed, if you want to allow all use *. It can be a pattern like
+	// AllowedOrigins
 	AllowedOrigin string
 
+	// AllowedOrigins is a list of origins which are allowed, like https://www.example.com. An origin can contain one
+	// * matching any part of a host name or port, so https://*.example.com allows https://api.example.com but not
+	// https://example.com. The * only matches letters, digits, dots and dashes, or only digits in place of a port.
+	// The list can contain * to allow all origins
+	AllowedOrigins []string
+
+	// AllowOriginFunc is called for the origins which are not allowed by AllowedOrigin or AllowedOrigins, the origin is
+	// allowed when it returns true
+	AllowOriginFunc func(origin string, request *http.Request) bool
+
 	// AllowedCredentials indicates whether the response to the request can be exposed when the credentials flag is true.
 	// The only valid value for this header is true (case-sensitive). If you don't need credentials,
-	// omit this header entirely (rather than setting its value to false).
+	// omit this header entirely (rather than setting its value to false). It is ignored when all origins are allowed,
+	// as letting any site make credentialed requests would expose the data of the users to it.
 	AllowedCredentials bool
 
 	// AllowedHeaders is used in response to a pre-flight request which includes the Access-Control-Request-Headers
-	// to indicate which HTTP headers can be used during the actual request.
+	// to indicate which HTTP headers can be used during the actual request. Use * to allow all headers.
 	AllowedHeaders []string
 
 	// AllowedMethods is a list of methods the client is allowed to use with
@@ -31,82 +43,314 @@ type CORS struct {
 	// Access-Control-Allow-Methods and Access-Control-Allow-Headers headers) can be cached.
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Access-Control-Max-Age
 	MaxAge int
+
+	// AllowPrivateNetwork allows public websites to make requests to a server in a private network, it answers
+	// pre-flight requests sending Access-Control-Request-Private-Network with Access-Control-Allow-Private-Network
+	AllowPrivateNetwork bool
 }
 
-// CORSHandler handles the simple and pre-flight requests
+// CORSHandler handles the simple and pre-flight requests. The Origin of a request is echoed in
+// Access-Control-Allow-Origin when it is allowed, * is sent when all origins are allowed and never with credentials.
+// Requests from other origins get no CORS headers, and Vary: Origin is added whenever the response depends on the
+// origin so caches keep the responses for different origins apart.
+//
+// A pre-flight request is answered with 204 No Content without calling h, it only gets the CORS headers when its
+// origin, Access-Control-Request-Method and Access-Control-Request-Headers are allowed. Register the handler with
+// Router.Use, or for the OPTIONS method of the route, as the router answers the OPTIONS requests of other routes
+// itself. The configuration is copied when CORSHandler is called, changing it afterwards has no effect
 func (cors CORS) CORSHandler(h http.HandlerFunc) http.HandlerFunc {
+	config := cors.compile()
+
 	return func(w http.ResponseWriter, request *http.Request) {
-		if request.Method == http.MethodOptions {
-			// Handling pre-flight requests
-			cors.handlePreflight(w, request)
-			h.ServeHTTP(w, request)
-
-		} else {
-			// Handling simple request
-			cors.handleSimple(w, request)
-			h.ServeHTTP(w, request)
+		if isPreflight(request) {
+			config.handlePreflight(w, request)
+			return
+		}
+
+		config.handleSimple(w, request)
+		h.ServeHTTP(w, request)
+	}
+}
+
+// safelistedMethods are the methods a cross-origin request can always use
+var safelistedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// corsConfig is a CORS configuration prepared by CORSHandler, it is only read while handling requests so concurrent
+// requests can share it
+type corsConfig struct {
+	origins            originMatcher
+	allowedCredentials bool
+	// allowedMethods contains the upper cased methods which are allowed
+	allowedMethods map[string]bool
+	// allowedHeaders contains the lower cased headers which are allowed, allHeaders tells if any header is
+	allowedHeaders map[string]bool
+	allHeaders     bool
+	// methods, exposedHeaders and maxAge are the values of the headers sent with every response
+	methods             string
+	exposedHeaders      string
+	maxAge              string
+	allowPrivateNetwork bool
+}
+
+// compile prepares the configuration, the lists are copied so the configuration does not share them with cors
+func (cors CORS) compile() *corsConfig {
+	config := &corsConfig{
+		origins:             cors.originMatcher(),
+		allowedCredentials:  cors.AllowedCredentials,
+		allowedMethods:      make(map[string]bool),
+		allowedHeaders:      make(map[string]bool),
+		allowPrivateNetwork: cors.AllowPrivateNetwork,
+	}
+
+	// * can not be sent with credentials and echoing every origin instead would let any site read the responses of
+	// the users, so credentials are dropped when all origins are allowed
+	if config.origins.all {
+		config.allowedCredentials = false
+	}
+
+	methods := cors.AllowedMethods
+	if len(methods) == 0 {
+		methods = safelistedMethods
+	}
+
+	methodNames := make([]string, 0, len(methods))
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method != "" && !config.allowedMethods[method] {
+			config.allowedMethods[method] = true
+			methodNames = append(methodNames, method)
 		}
 	}
+	config.methods = strings.Join(methodNames, ", ")
+
+	for _, header := range cors.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			config.allHeaders = true
+		} else if header != "" {
+			config.allowedHeaders[strings.ToLower(header)] = true
+		}
+	}
+
+	exposedHeaders := make([]string, 0, len(cors.ExposedHeaders))
+	for _, header := range cors.ExposedHeaders {
+		exposedHeaders = append(exposedHeaders, http.CanonicalHeaderKey(strings.TrimSpace(header)))
+	}
+	config.exposedHeaders = strings.Join(exposedHeaders, ", ")
+
+	if cors.MaxAge != 0 {
+		config.maxAge = strconv.Itoa(cors.MaxAge)
+	}
+
+	return config
+}
+
+// isPreflight tells if the request is a pre-flight request, an OPTIONS request asking if a cross-origin request with
+// the method in Access-Control-Request-Method is allowed
+func isPreflight(request *http.Request) bool {
+	return request.Method == http.MethodOptions &&
+		request.Header.Get("Origin") != "" &&
+		request.Header.Get("Access-Control-Request-Method") != ""
 }
 
 // handleSimple handles the simple requests
-func (cors CORS) handleSimple(w http.ResponseWriter, request *http.Request) {
-	if cors.AllowedOrigin != "" {
-		w.Header().Set("Access-Control-Allow-Origin", cors.AllowedOrigin)
+func (config *corsConfig) handleSimple(w http.ResponseWriter, request *http.Request) {
+	if config.origins.varies() {
+		w.Header().Add("Vary", "Origin")
+	}
+
+	origin, ok := config.origins.allowed(request)
+	if !ok {
+		return
 	}
 
-	if cors.AllowedCredentials {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+
+	if config.allowedCredentials {
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 	}
 
-	if len(cors.AllowedHeaders) > 0 {
-		for idx, header := range cors.AllowedHeaders {
-			cors.AllowedHeaders[idx] = http.CanonicalHeaderKey(header)
-		}
-		w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
+	if config.exposedHeaders != "" {
+		w.Header().Set("Access-Control-Expose-Headers", config.exposedHeaders)
+	}
+}
+
+// handlePreflight handles the pre-flight requests
+func (config *corsConfig) handlePreflight(w http.ResponseWriter, request *http.Request) {
+	header := w.Header()
+
+	header.Add("Vary", "Origin")
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	if config.allowPrivateNetwork {
+		header.Add("Vary", "Access-Control-Request-Private-Network")
+	}
+
+	origin, ok := config.origins.allowed(request)
+	requestedHeaders, headersAllowed := config.allowHeaders(request.Header.Values("Access-Control-Request-Headers"))
+
+	if !ok || !config.allowMethod(request.Header.Get("Access-Control-Request-Method")) || !headersAllowed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+
+	if config.allowedCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
 	}
 
-	if len(cors.AllowedMethods) > 0 {
-		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+	header.Set("Access-Control-Allow-Methods", config.methods)
+
+	if len(requestedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+
+	if config.maxAge != "" {
+		header.Set("Access-Control-Max-Age", config.maxAge)
 	}
 
-	if len(cors.ExposedHeaders) > 0 {
-		for idx, header := range cors.ExposedHeaders {
-			cors.ExposedHeaders[idx] = http.CanonicalHeaderKey(header)
+	if config.allowPrivateNetwork && request.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		header.Set("Access-Control-Allow-Private-Network", "true")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// allowMethod tells if a cross-origin request can use the method
+func (config *corsConfig) allowMethod(method string) bool {
+	method = strings.ToUpper(method)
+
+	for _, safelisted := range safelistedMethods {
+		if method == safelisted {
+			return true
 		}
-		w.Header().Set("Access-Control-Expose-Headers", strings.Join(cors.ExposedHeaders, ", "))
 	}
 
-	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	return config.allowedMethods[method]
 }
 
-// handlePreflight handles the pre-flight requests
-func (cors CORS) handlePreflight(w http.ResponseWriter, request *http.Request) {
-	if cors.AllowedOrigin != "" {
-		w.Header().Set("Access-Control-Allow-Origin", cors.AllowedOrigin)
+// allowHeaders parses the values of Access-Control-Request-Headers and tells if a cross-origin request can send all
+// of the headers, it returns the lower cased header names
+func (config *corsConfig) allowHeaders(values []string) ([]string, bool) {
+	var headers []string
+
+	for _, value := range values {
+		for _, header := range strings.Split(value, ",") {
+			header = strings.ToLower(strings.TrimSpace(header))
+			if header == "" {
+				continue
+			}
+
+			if !config.allHeaders && !config.allowedHeaders[header] {
+				return nil, false
+			}
+
+			headers = append(headers, header)
+		}
 	}
 
-	if cors.AllowedCredentials {
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	return headers, true
+}
+
+// originMatcher tells which origins are allowed by a CORS configuration
+type originMatcher struct {
+	// all tells if every origin is allowed
+	all bool
+	// exact contains the lower cased origins without a wildcard
+	exact map[string]bool
+	// patterns contains the lower cased origins with a wildcard, split at the wildcard
+	patterns [][2]string
+	// allowFunc is the AllowOriginFunc of the configuration
+	allowFunc func(origin string, request *http.Request) bool
+}
+
+// originMatcher compiles the allowed origins of the configuration once, so they are not parsed on every request
+func (cors CORS) originMatcher() originMatcher {
+	matcher := originMatcher{
+		exact:     make(map[string]bool),
+		allowFunc: cors.AllowOriginFunc,
+	}
+
+	origins := cors.AllowedOrigins
+	if cors.AllowedOrigin != "" {
+		origins = append([]string{cors.AllowedOrigin}, origins...)
 	}
 
-	if len(cors.AllowedHeaders) > 0 {
-		for idx, header := range cors.AllowedHeaders {
-			cors.AllowedHeaders[idx] = http.CanonicalHeaderKey(header)
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+
+		switch {
+		case origin == "*":
+			matcher.all = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			matcher.patterns = append(matcher.patterns, [2]string{prefix, suffix})
+		case origin != "":
+			matcher.exact[origin] = true
 		}
-		w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.AllowedHeaders, ", "))
 	}
 
-	if len(cors.AllowedMethods) > 0 {
-		w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.AllowedMethods, ", "))
+	return matcher
+}
+
+// varies tells if the Access-Control-Allow-Origin header depends on the origin of the request
+func (m originMatcher) varies() bool {
+	return !m.all
+}
+
+// allowed returns the value of Access-Control-Allow-Origin for the request and tells if its origin is allowed
+func (m originMatcher) allowed(request *http.Request) (string, bool) {
+	if m.all {
+		return "*", true
+	}
+
+	origin := request.Header.Get("Origin")
+	if origin == "" || !m.matches(origin, request) {
+		return "", false
+	}
+
+	return origin, true
+}
+
+// matches tells if the origin is allowed
+func (m originMatcher) matches(origin string, request *http.Request) bool {
+	lowered := strings.ToLower(origin)
+
+	if m.exact[lowered] {
+		return true
+	}
+
+	for _, pattern := range m.patterns {
+		prefix, suffix := pattern[0], pattern[1]
+		if len(lowered) <= len(prefix)+len(suffix) ||
+			!strings.HasPrefix(lowered, prefix) || !strings.HasSuffix(lowered, suffix) {
+			continue
+		}
+
+		if wildcardMatches(lowered[len(prefix):len(lowered)-len(suffix)], strings.HasSuffix(prefix, ":")) {
+			return true
+		}
 	}
 
-	if len(cors.ExposedHeaders) > 0 {
-		for idx, header := range cors.ExposedHeaders {
-			cors.ExposedHeaders[idx] = http.CanonicalHeaderKey(header)
+	return m.allowFunc != nil && m.allowFunc(origin, request)
+}
+
+// wildcardMatches tells if the part of an origin matched by the wildcard of a pattern only contains the characters of
+// a host name, or only digits when the wildcard replaces the port
+func wildcardMatches(part string, port bool) bool {
+	for idx := 0; idx < len(part); idx++ {
+		c := part[idx]
+		isDigit := c >= '0' && c <= '9'
+
+		if port && !isDigit {
+			return false
+		}
+
+		if !isDigit && (c < 'a' || c > 'z') && c != '.' && c != '-' {
+			return false
 		}
-		w.Header().Set("Access-Control-Expose-Headers", strings.Join(cors.ExposedHeaders, ", "))
 	}
 
-	w.Header().Set("Access-Control-Max-Age", strconv.Itoa(cors.MaxAge))
+	return true
 }
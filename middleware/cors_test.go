@@ -1,24 +1,35 @@
-package middleware
+package middleware_test
 
 import (
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
 	"github.com/flannel-dev-lab/cyclops/v2/router"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
 func TestCORS_CORSHandler(t *testing.T) {
-	cors := []CORS{
-		{"*", true, []string{"Content-Type"}, []string{"HEAD"}, []string{"Content-Type"}, 300},
+	cors := []middleware.CORS{
+		{
+			AllowedOrigin:      "*",
+			AllowedCredentials: true,
+			AllowedHeaders:     []string{"Content-Type"},
+			AllowedMethods:     []string{"HEAD"},
+			ExposedHeaders:     []string{"Content-Type"},
+			MaxAge:             300,
+		},
 	}
 
 	for _, testCase := range cors {
 		r := router.New(true, nil, nil)
-		r.Post("/use", NewChain(testCase.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {}))
-		r.Options("/use", NewChain(testCase.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {}))
+		r.Post("/use", middleware.NewChain(testCase.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {}))
+		r.Options("/use", middleware.NewChain(testCase.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {}))
 
+		// Credentials are dropped for all origins, neither is an origin echoed nor credentials allowed
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest("POST", "/use", nil)
+		req.Header.Set("Origin", "https://evil.attacker")
 
 		r.ServeHTTP(w, req)
 
@@ -26,13 +37,236 @@ func TestCORS_CORSHandler(t *testing.T) {
 			t.Errorf("expected %s got %s", testCase.AllowedOrigin, w.Header().Get("Access-Control-Allow-Origin"))
 		}
 
+		if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("expected no credentials got %s", w.Header().Get("Access-Control-Allow-Credentials"))
+		}
+
 		w = httptest.NewRecorder()
 		req, _ = http.NewRequest("OPTIONS", "/use", nil)
+		req.Header.Set("Origin", "https://evil.attacker")
 
 		r.ServeHTTP(w, req)
 
 		if w.Header().Get("Access-Control-Allow-Origin") != testCase.AllowedOrigin {
 			t.Errorf("expected %s got %s", testCase.AllowedOrigin, w.Header().Get("Access-Control-Allow-Origin"))
 		}
+
+		if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+			t.Errorf("expected no credentials got %s", w.Header().Get("Access-Control-Allow-Credentials"))
+		}
+	}
+}
+
+func TestCORS_AllowedOrigins(t *testing.T) {
+	cors := middleware.CORS{
+		AllowedOrigin:  "https://app.example.org",
+		AllowedOrigins: []string{"https://www.example.com", "https://*.example.com", "http://localhost:*"},
+		AllowOriginFunc: func(origin string, request *http.Request) bool {
+			return origin == "https://partner.test" && request.Header.Get("X-Partner") == "yes"
+		},
+		AllowedCredentials: true,
+	}
+
+	handler := middleware.NewPlainChain(cors.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		Origin  string
+		Partner bool
+		Allowed bool
+	}{
+		{"https://app.example.org", false, true},
+		{"https://www.example.com", false, true},
+		{"HTTPS://WWW.EXAMPLE.COM", false, true},
+		{"https://api.example.com", false, true},
+		{"https://eu.api.example.com", false, true},
+		{"http://localhost:3000", false, true},
+		{"https://partner.test", true, true},
+		{"https://partner.test", false, false},
+		{"https://example.com", false, false},
+		{"https://evil.com/.example.com", false, false},
+		{"https://evil.com?.example.com", false, false},
+		{"https://evil.com#.example.com", false, false},
+		{"http://localhost:3000.evil.com", false, false},
+		{"https://example.com.evil.com", false, false},
+		{"http://api.example.com", false, false},
+		{"", false, false},
+	}
+
+	for _, testCase := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if testCase.Origin != "" {
+			req.Header.Set("Origin", testCase.Origin)
+		}
+		if testCase.Partner {
+			req.Header.Set("X-Partner", "yes")
+		}
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		expected := ""
+		if testCase.Allowed {
+			expected = testCase.Origin
+		}
+
+		if w.Header().Get("Access-Control-Allow-Origin") != expected {
+			t.Errorf("%s: %q: expected %q got %q", t.Name(), testCase.Origin, expected, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+
+		if credentials := w.Header().Get("Access-Control-Allow-Credentials") == "true"; credentials != testCase.Allowed {
+			t.Errorf("%s: %q: expected credentials to be allowed %t", t.Name(), testCase.Origin, testCase.Allowed)
+		}
+
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("%s: %q: expected Vary Origin got %q", t.Name(), testCase.Origin, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestCORS_AllOrigins(t *testing.T) {
+	cors := middleware.CORS{AllowedOrigins: []string{"*"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://any.example.com")
+	w := httptest.NewRecorder()
+
+	middleware.NewPlainChain(cors.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {})(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("%s: expected * got %q", t.Name(), w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	if w.Header().Get("Vary") != "" {
+		t.Errorf("%s: expected no Vary for all origins got %q", t.Name(), w.Header().Get("Vary"))
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	cors := middleware.CORS{
+		AllowedOrigins:      []string{"https://www.example.com"},
+		AllowedHeaders:      []string{"content-type", "X-Request-ID"},
+		AllowedMethods:      []string{"PUT", "delete"},
+		ExposedHeaders:      []string{"x-total-count"},
+		MaxAge:              600,
+		AllowPrivateNetwork: true,
+	}
+
+	called := false
+	r := router.New(false, nil, nil)
+	r.Use(cors.CORSHandler)
+	r.Put("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	cases := []struct {
+		Origin         string
+		Method         string
+		Headers        string
+		PrivateNetwork bool
+		Allowed        bool
+	}{
+		{"https://www.example.com", "PUT", "Content-Type, x-request-id", false, true},
+		{"https://www.example.com", "DELETE", "", true, true},
+		{"https://www.example.com", "GET", "", false, true},
+		{"https://www.example.com", "PATCH", "", false, false},
+		{"https://www.example.com", "PUT", "Content-Type, Authorization", false, false},
+		{"https://evil.com", "PUT", "", false, false},
+	}
+
+	for _, testCase := range cases {
+		called = false
+
+		req := httptest.NewRequest(http.MethodOptions, "/users/1", nil)
+		req.Header.Set("Origin", testCase.Origin)
+		req.Header.Set("Access-Control-Request-Method", testCase.Method)
+		if testCase.Headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", testCase.Headers)
+		}
+		if testCase.PrivateNetwork {
+			req.Header.Set("Access-Control-Request-Private-Network", "true")
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: %+v: expected %d got %d", t.Name(), testCase, http.StatusNoContent, w.Code)
+		}
+
+		if called {
+			t.Errorf("%s: %+v: expected the handler to not be called", t.Name(), testCase)
+		}
+
+		if allowed := w.Header().Get("Access-Control-Allow-Origin") == testCase.Origin; allowed != testCase.Allowed {
+			t.Errorf("%s: %+v: expected allowed to be %t got headers %v", t.Name(), testCase, testCase.Allowed, w.Header())
+		}
+
+		if !testCase.Allowed {
+			continue
+		}
+
+		if w.Header().Get("Access-Control-Allow-Methods") != "PUT, DELETE" {
+			t.Errorf("%s: %+v: expected methods %q got %q", t.Name(), testCase, "PUT, DELETE", w.Header().Get("Access-Control-Allow-Methods"))
+		}
+
+		if testCase.Headers != "" && w.Header().Get("Access-Control-Allow-Headers") != "content-type, x-request-id" {
+			t.Errorf("%s: %+v: expected headers %q got %q", t.Name(), testCase, "content-type, x-request-id", w.Header().Get("Access-Control-Allow-Headers"))
+		}
+
+		if w.Header().Get("Access-Control-Max-Age") != "600" {
+			t.Errorf("%s: %+v: expected max age 600 got %q", t.Name(), testCase, w.Header().Get("Access-Control-Max-Age"))
+		}
+
+		if privateNetwork := w.Header().Get("Access-Control-Allow-Private-Network") == "true"; privateNetwork != testCase.PrivateNetwork {
+			t.Errorf("%s: %+v: expected private network to be allowed %t", t.Name(), testCase, testCase.PrivateNetwork)
+		}
+	}
+
+	// The actual request reaches the handler and gets the exposed headers
+	req := httptest.NewRequest(http.MethodPut, "/users/1", nil)
+	req.Header.Set("Origin", "https://www.example.com")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if !called {
+		t.Errorf("%s: expected the handler to be called", t.Name())
+	}
+
+	if w.Header().Get("Access-Control-Expose-Headers") != "X-Total-Count" {
+		t.Errorf("%s: expected exposed headers %q got %q", t.Name(), "X-Total-Count", w.Header().Get("Access-Control-Expose-Headers"))
+	}
+}
+
+func TestCORS_Concurrent(t *testing.T) {
+	cors := middleware.CORS{
+		AllowedOrigin:  "https://www.example.com",
+		AllowedHeaders: []string{"content-type"},
+		ExposedHeaders: []string{"x-total-count"},
+	}
+
+	handler := middleware.NewPlainChain(cors.CORSHandler).Then(func(w http.ResponseWriter, r *http.Request) {})
+
+	var wg sync.WaitGroup
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+		go func(preflight bool) {
+			defer wg.Done()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if preflight {
+				req.Method = http.MethodOptions
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				req.Header.Set("Access-Control-Request-Headers", "content-type")
+			}
+			req.Header.Set("Origin", "https://www.example.com")
+
+			handler(httptest.NewRecorder(), req)
+		}(idx%2 == 0)
+	}
+	wg.Wait()
+
+	if cors.AllowedHeaders[0] != "content-type" || cors.ExposedHeaders[0] != "x-total-count" {
+		t.Errorf("%s: expected the configuration to be unchanged got %v %v", t.Name(), cors.AllowedHeaders, cors.ExposedHeaders)
 	}
 }
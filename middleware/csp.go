@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// Sources of a Content-Security-Policy directive, hosts and schemes like https://cdn.example.com or data: can be used
+// as well
+const (
+	SourceSelf          = "'self'"
+	SourceNone          = "'none'"
+	SourceUnsafeInline  = "'unsafe-inline'"
+	SourceUnsafeEval    = "'unsafe-eval'"
+	SourceStrictDynamic = "'strict-dynamic'"
+	SourceData          = "data:"
+	SourceBlob          = "blob:"
+	SourceHTTPS         = "https:"
+	// SourceNonce is replaced by a nonce generated for every request, the nonce is read with Nonce to set it on the
+	// inline scripts and styles of the response
+	SourceNonce = "'nonce'"
+)
+
+// ContentSecurityPolicy builds the value of a Content-Security-Policy header, directives are added with its methods
+// and sent in the order they were added
+//
+//	csp := middleware.NewContentSecurityPolicy().
+//		DefaultSrc(middleware.SourceSelf).
+//		ScriptSrc(middleware.SourceSelf, middleware.SourceNonce).
+//		ReportURI("/csp-reports")
+type ContentSecurityPolicy struct {
+	directives []cspDirective
+	// ReportOnly sends the policy as Content-Security-Policy-Report-Only, so violations are reported but not blocked
+	ReportOnly bool
+}
+
+// cspDirective is a directive of a ContentSecurityPolicy with its values
+type cspDirective struct {
+	name   string
+	values []string
+}
+
+// NewContentSecurityPolicy creates an empty policy
+func NewContentSecurityPolicy() *ContentSecurityPolicy {
+	return &ContentSecurityPolicy{}
+}
+
+// Directive adds a directive with its values, adding a directive again replaces its values. It can be used for the
+// directives without a method of their own
+func (csp *ContentSecurityPolicy) Directive(name string, values ...string) *ContentSecurityPolicy {
+	directive := cspDirective{name: name, values: append([]string(nil), values...)}
+
+	for idx, existing := range csp.directives {
+		if existing.name == name {
+			csp.directives[idx] = directive
+			return csp
+		}
+	}
+
+	csp.directives = append(csp.directives, directive)
+
+	return csp
+}
+
+// DefaultSrc sets the sources of the fetch directives which are not set
+func (csp *ContentSecurityPolicy) DefaultSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("default-src", sources...)
+}
+
+// ScriptSrc sets the sources of scripts
+func (csp *ContentSecurityPolicy) ScriptSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("script-src", sources...)
+}
+
+// StyleSrc sets the sources of stylesheets
+func (csp *ContentSecurityPolicy) StyleSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("style-src", sources...)
+}
+
+// ImgSrc sets the sources of images
+func (csp *ContentSecurityPolicy) ImgSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("img-src", sources...)
+}
+
+// ConnectSrc sets the URLs scripts can connect to, like with fetch or a WebSocket
+func (csp *ContentSecurityPolicy) ConnectSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("connect-src", sources...)
+}
+
+// FontSrc sets the sources of fonts
+func (csp *ContentSecurityPolicy) FontSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("font-src", sources...)
+}
+
+// ObjectSrc sets the sources of <object> and <embed> elements
+func (csp *ContentSecurityPolicy) ObjectSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("object-src", sources...)
+}
+
+// MediaSrc sets the sources of <audio> and <video> elements
+func (csp *ContentSecurityPolicy) MediaSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("media-src", sources...)
+}
+
+// FrameSrc sets the sources of frames
+func (csp *ContentSecurityPolicy) FrameSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("frame-src", sources...)
+}
+
+// WorkerSrc sets the sources of workers
+func (csp *ContentSecurityPolicy) WorkerSrc(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("worker-src", sources...)
+}
+
+// FrameAncestors sets the parents which can embed the page in a frame
+func (csp *ContentSecurityPolicy) FrameAncestors(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("frame-ancestors", sources...)
+}
+
+// BaseURI sets the URLs which can be used in the <base> element
+func (csp *ContentSecurityPolicy) BaseURI(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("base-uri", sources...)
+}
+
+// FormAction sets the URLs forms can be submitted to
+func (csp *ContentSecurityPolicy) FormAction(sources ...string) *ContentSecurityPolicy {
+	return csp.Directive("form-action", sources...)
+}
+
+// UpgradeInsecureRequests makes browsers request the http URLs of the page over https
+func (csp *ContentSecurityPolicy) UpgradeInsecureRequests() *ContentSecurityPolicy {
+	return csp.Directive("upgrade-insecure-requests")
+}
+
+// ReportURI sets the URL violations are reported to
+func (csp *ContentSecurityPolicy) ReportURI(uri string) *ContentSecurityPolicy {
+	return csp.Directive("report-uri", uri)
+}
+
+// ReportTo sets the Reporting-Endpoints group violations are reported to
+func (csp *ContentSecurityPolicy) ReportTo(group string) *ContentSecurityPolicy {
+	return csp.Directive("report-to", group)
+}
+
+// String returns the value of the header, with SourceNonce left in place of the nonce
+func (csp *ContentSecurityPolicy) String() string {
+	directives := make([]string, 0, len(csp.directives))
+	for _, directive := range csp.directives {
+		directives = append(directives, strings.Join(append([]string{directive.name}, directive.values...), " "))
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// headerName returns the name of the header the policy is sent in
+func (csp *ContentSecurityPolicy) headerName() string {
+	if csp.ReportOnly {
+		return "Content-Security-Policy-Report-Only"
+	}
+
+	return "Content-Security-Policy"
+}
+
+// nonceKey is the context key the nonce of a request is stored under
+type nonceKey struct{}
+
+// Nonce returns the nonce generated for the Content-Security-Policy of the request, it is empty when the policy does
+// not use SourceNonce. It is set on inline scripts and styles like <script nonce="{{.Nonce}}">
+func Nonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(nonceKey{}).(string)
+	return nonce
+}
+
+// newNonce generates a random nonce
+func newNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(nonce), nil
+}
@@ -0,0 +1,77 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
+)
+
+func TestContentSecurityPolicy_String(t *testing.T) {
+	csp := middleware.NewContentSecurityPolicy().
+		DefaultSrc(middleware.SourceSelf).
+		ImgSrc(middleware.SourceSelf, middleware.SourceData).
+		ObjectSrc(middleware.SourceNone).
+		Directive("sandbox", "allow-forms").
+		UpgradeInsecureRequests().
+		ImgSrc(middleware.SourceSelf).
+		ReportURI("/csp-reports")
+
+	expected := "default-src 'self'; img-src 'self'; object-src 'none'; sandbox allow-forms; upgrade-insecure-requests; report-uri /csp-reports"
+	if csp.String() != expected {
+		t.Errorf("%s: expected %q got %q", t.Name(), expected, csp.String())
+	}
+}
+
+func TestContentSecurityPolicy_Nonce(t *testing.T) {
+	csp := middleware.NewContentSecurityPolicy().
+		ScriptSrc(middleware.SourceNonce, middleware.SourceStrictDynamic).
+		StyleSrc(middleware.SourceSelf, middleware.SourceNonce)
+	csp.ReportOnly = true
+
+	secureHeaders := middleware.SecureHeaders{ContentSecurityPolicy: csp}
+
+	var nonces []string
+	handler := middleware.NewPlainChain(secureHeaders.SetSecureHeaders).Then(func(w http.ResponseWriter, r *http.Request) {
+		nonces = append(nonces, middleware.Nonce(r.Context()))
+	})
+
+	for idx := 0; idx < 2; idx++ {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		nonce := nonces[idx]
+		if nonce == "" {
+			t.Fatalf("%s: expected a nonce in the context", t.Name())
+		}
+
+		expected := "script-src 'nonce-" + nonce + "' 'strict-dynamic'; style-src 'self' 'nonce-" + nonce + "'"
+		if w.Header().Get("Content-Security-Policy-Report-Only") != expected {
+			t.Errorf("%s: expected %q got %q", t.Name(), expected, w.Header().Get("Content-Security-Policy-Report-Only"))
+		}
+
+		if w.Header().Get("Content-Security-Policy") != "" {
+			t.Errorf("%s: expected no enforced policy in report only mode", t.Name())
+		}
+	}
+
+	if nonces[0] == nonces[1] {
+		t.Errorf("%s: expected a new nonce for every request got %q twice", t.Name(), nonces[0])
+	}
+
+	// A policy without SourceNonce does not generate nonces
+	secureHeaders = middleware.SecureHeaders{ContentSecurityPolicy: middleware.NewContentSecurityPolicy().DefaultSrc(middleware.SourceSelf)}
+
+	w := httptest.NewRecorder()
+	middleware.NewPlainChain(secureHeaders.SetSecureHeaders).Then(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.Nonce(r.Context()) != "" {
+			t.Errorf("%s: expected no nonce", t.Name())
+		}
+	})(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.HasPrefix(w.Header().Get("Content-Security-Policy"), "default-src 'self'") {
+		t.Errorf("%s: expected the policy got %q", t.Name(), w.Header().Get("Content-Security-Policy"))
+	}
+}
@@ -1,7 +1,8 @@
 // middleware package includes different middleware available by default with cyclops, cyclops is made in such a way
 // that  it is easy for developers to plug custom middleware as well, the only thing that the developer need to do is
-// write a middleware that takes in a http.Handler and returns a http.Handler, once the middleware is complete pass it
-// to NewChain method to start using it
+// write a middleware that takes in a http.HandlerFunc and returns a http.HandlerFunc, once the middleware is complete
+// pass it to NewChain method to start using it. Middleware taking in a http.Handler and returning a http.Handler is
+// adapted with FromHandler
 package middleware
 
 import (
@@ -15,20 +16,69 @@ type Middlewares func(http.HandlerFunc) http.HandlerFunc
 // Chain contains a slice of middleware for the request
 type Chain struct {
 	middlewareHandlers []Middlewares
+	// defaults tells if Then wraps the handlers with the Defaults
+	defaults bool
 }
 
-// NewChain takes a variable number of middleware's and adds them to chain and returns a pointer to Chain
+// NewChain takes a variable number of middleware's and adds them to chain and returns a pointer to Chain, Then wraps
+// the handlers of the chain with the Defaults as well
 func NewChain(middlewares ...Middlewares) *Chain {
+	return &Chain{middlewareHandlers: middlewares, defaults: true}
+}
+
+// NewPlainChain is like NewChain but Then does not wrap the handlers with the Defaults, so they can be replaced or
+// added in another order
+func NewPlainChain(middlewares ...Middlewares) *Chain {
 	return &Chain{middlewareHandlers: middlewares}
 }
 
+// Defaults returns the middleware Then adds to a chain created by NewChain, in the order they are chained in. The
+// PanicHandler recovers the panics of the handler and the AccessLogger around it logs the request
+func Defaults() []Middlewares {
+	return []Middlewares{PanicHandler, AccessLogger}
+}
+
+// Append returns a new chain with the middleware's added after the middleware's of the chain, so they wrap them
+func (chain *Chain) Append(middlewares ...Middlewares) *Chain {
+	return chain.with(chain.middlewareHandlers, middlewares)
+}
+
+// Prepend returns a new chain with the middleware's added before the middleware's of the chain, so they are wrapped
+// by them
+func (chain *Chain) Prepend(middlewares ...Middlewares) *Chain {
+	return chain.with(middlewares, chain.middlewareHandlers)
+}
+
+// Extend returns a new chain with the middleware's of other added after the middleware's of the chain, the new
+// chain adds the Defaults when the chain does
+func (chain *Chain) Extend(other *Chain) *Chain {
+	return chain.with(chain.middlewareHandlers, other.middlewareHandlers)
+}
+
+// with returns a new chain with the middleware's of first followed by the ones of second, it copies them so the
+// chains do not share their middleware's
+func (chain *Chain) with(first, second []Middlewares) *Chain {
+	middlewares := make([]Middlewares, 0, len(first)+len(second))
+	middlewares = append(middlewares, first...)
+	middlewares = append(middlewares, second...)
+
+	return &Chain{middlewareHandlers: middlewares, defaults: chain.defaults}
+}
+
 // Then will take in your handler that need to be executed with the requested path and chains all the middleware's that
 // are  specified in Chain, the note here is that middleware's are chained in the order they are specified,
-// so take care of adding middleware's in appropriate order
+// so take care of adding middleware's in appropriate order. A chain created by NewChain wraps the handler with the
+// Defaults last
 func (chain *Chain) Then(handler http.HandlerFunc) http.HandlerFunc {
 	for _, middleware := range chain.middlewareHandlers {
 		handler = middleware(handler)
 	}
-	handler = AccessLogger(PanicHandler(handler))
+
+	if chain.defaults {
+		for _, middleware := range Defaults() {
+			handler = middleware(handler)
+		}
+	}
+
 	return handler
 }
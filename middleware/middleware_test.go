@@ -0,0 +1,131 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
+)
+
+// trace returns a middleware recording its name in calls before calling the next handler
+func trace(name string, calls *[]string) middleware.Middlewares {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name)
+			next(w, r)
+		}
+	}
+}
+
+func TestChain_Compose(t *testing.T) {
+	var calls []string
+
+	base := middleware.NewPlainChain(trace("b", &calls))
+	other := middleware.NewPlainChain(trace("x", &calls), trace("y", &calls))
+
+	cases := []struct {
+		Chain    *middleware.Chain
+		Expected []string
+	}{
+		{base, []string{"b"}},
+		{base.Append(trace("c", &calls)), []string{"c", "b"}},
+		{base.Prepend(trace("a", &calls)), []string{"b", "a"}},
+		{base.Extend(other), []string{"y", "x", "b"}},
+		{base.Append(trace("c", &calls)).Prepend(trace("a", &calls)), []string{"c", "b", "a"}},
+	}
+
+	for _, testCase := range cases {
+		calls = nil
+
+		handler := testCase.Chain.Then(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		})
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		expected := append(testCase.Expected, "handler")
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("%s: expected %v got %v", t.Name(), expected, calls)
+		}
+	}
+
+	// Composing a chain does not modify it
+	calls = nil
+	base.Then(func(w http.ResponseWriter, r *http.Request) {})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !reflect.DeepEqual(calls, []string{"b"}) {
+		t.Errorf("%s: expected the base chain to be unchanged got %v", t.Name(), calls)
+	}
+}
+
+func TestChain_Defaults(t *testing.T) {
+	panics := func(w http.ResponseWriter, r *http.Request) {
+		panic("handler panic")
+	}
+
+	w := httptest.NewRecorder()
+	middleware.NewChain().Then(panics)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("%s: expected the default PanicHandler to answer 500 got %d", t.Name(), w.Code)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected a plain chain to not recover the panic", t.Name())
+		}
+	}()
+
+	middleware.NewPlainChain().Then(panics)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+// captureStdout returns what fn prints to the standard output, where the logger writes to
+func captureStdout(t *testing.T, fn func()) string {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	fn()
+
+	_ = writer.Close()
+	output, _ := io.ReadAll(reader)
+
+	return string(output)
+}
+
+func TestSkipAccessLog(t *testing.T) {
+	cases := []struct {
+		Path    string
+		Chain   *middleware.Chain
+		Logged  bool
+		Comment string
+	}{
+		{"/users", middleware.NewChain(), true, "default chain"},
+		{"/healthz", middleware.NewChain(middleware.SkipAccessLog), false, "default chain"},
+		{"/healthz", middleware.NewPlainChain(middleware.SkipAccessLog, middleware.AccessLogger, middleware.AccessLogger), false, "nested loggers"},
+		{"/healthz", middleware.NewPlainChain(middleware.AccessLogger, middleware.SkipAccessLog), true, "marker outside of the logger"},
+	}
+
+	for _, testCase := range cases {
+		handler := testCase.Chain.Then(func(w http.ResponseWriter, r *http.Request) {})
+
+		output := captureStdout(t, func() {
+			handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, testCase.Path, nil))
+		})
+
+		if logged := strings.Contains(output, "access_log"); logged != testCase.Logged {
+			t.Errorf("%s: %s: expected logged to be %t got output %q", t.Name(), testCase.Comment, testCase.Logged, output)
+		}
+	}
+}
@@ -1,7 +1,8 @@
-package middleware
+package middleware_test
 
 import (
 	"errors"
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
 	"github.com/flannel-dev-lab/cyclops/v2/router"
 	"net/http"
 	"net/http/httptest"
@@ -17,7 +18,7 @@ func TestPanicHandler(t *testing.T) {
 
 	for _, testCase := range cases {
 		r := router.New(true, nil, nil)
-		r.Post("/use", NewChain(PanicHandler).Then(func(w http.ResponseWriter, r *http.Request) {
+		r.Post("/use", middleware.NewChain(middleware.PanicHandler).Then(func(w http.ResponseWriter, r *http.Request) {
 			panic(testCase)
 		}))
 
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/flannel-dev-lab/cyclops/v2/logger"
@@ -38,11 +39,35 @@ func (lrw *loggingResponseWriter) Write(data []byte) (int, error) {
 	return lrw.ResponseWriter.Write(data)
 }
 
-// AccessLogger is used to log access logs for discover service
+// skipAccessLogKey is the context key of the flag SkipAccessLog sets to stop the AccessLogger from logging a request
+type skipAccessLogKey struct{}
+
+// SkipAccessLog stops the AccessLogger wrapping the handler from logging its requests, like for a health check
+// polled every few seconds
+func SkipAccessLog(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if skip, ok := r.Context().Value(skipAccessLogKey{}).(*bool); ok {
+			*skip = true
+		}
+
+		h.ServeHTTP(w, r)
+	}
+}
+
+// AccessLogger is used to log access logs for discover service, the requests of handlers wrapped by SkipAccessLog
+// are not logged
 func AccessLogger(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		ctx := r.Context()
+
+		// The flag is shared with an AccessLogger wrapping this one, so a skipped request is not logged by either
+		skip, ok := ctx.Value(skipAccessLogKey{}).(*bool)
+		if !ok {
+			skip = new(bool)
+			ctx = context.WithValue(ctx, skipAccessLogKey{}, skip)
+		}
+
 		ctx = logger.AddKey(ctx, "timestamp", time.Now().UTC().Format(time.RFC3339))
 		ctx = logger.AddKey(ctx, "remote_address", r.RemoteAddr)
 		ctx = logger.AddKey(ctx, "method", r.Method)
@@ -64,6 +89,10 @@ func AccessLogger(h http.HandlerFunc) http.HandlerFunc {
 
 		h.ServeHTTP(lrw, r)
 
+		if *skip {
+			return
+		}
+
 		ctx = logger.AddKey(ctx, "status_code", fmt.Sprintf("%d", lrw.statusCode))
 		ctx = logger.AddKey(ctx, "duration", fmt.Sprintf("%d", time.Since(startTime).Milliseconds()))
 
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -24,6 +27,8 @@ type DefaultHeaders struct {
 	LastModified string
 }
 
+// SecureHeaders lets you set the security headers of the responses, the headers without a default are only set when
+// they are configured
 type SecureHeaders struct {
 	// Sets the X-XSS-Protection Header. Valid values are https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/X-XSS-Protection
 	// Default is 1; mode=block
@@ -38,16 +43,42 @@ type SecureHeaders struct {
 	FrameOptions string
 	// Sets the `Strict-Transport-Security` header to indicate how
 	// long (in seconds) browsers should remember that this site is only to
-	// be accessed using HTTPS. Default is 0
+	// be accessed using HTTPS. Default is 0, which does not send the header. It is only sent over TLS
 	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Strict-Transport-Security
-	// HSTSMaxAge int
+	HSTSMaxAge int
 	// When enabled this rule applies to all the site's subdomains as well to Strict-Transport-Security
-	// HSTSIncludeSubdomains bool
+	HSTSIncludeSubdomains bool
+	// HSTSPreload asks for the site to be included in the HSTS preload list of browsers, which requires
+	// HSTSIncludeSubdomains and a HSTSMaxAge of at least a year
+	HSTSPreload bool
+	// HSTSTrustForwardedProto sends Strict-Transport-Security for requests with X-Forwarded-Proto: https as well, for
+	// servers behind a proxy terminating TLS. Only enable it when the proxy sets the header
+	HSTSTrustForwardedProto bool
 
 	// ReferrerPolicy sets the `Referrer-Policy` header providing security against
 	// leaking potentially sensitive request paths to third parties.
 	// Optional. Default value "".  https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Referrer-Policy
 	ReferrerPolicy string
+
+	// ContentSecurityPolicy sets the `Content-Security-Policy` header, or `Content-Security-Policy-Report-Only` when
+	// the policy is report only. When it uses SourceNonce a nonce is generated for every request, read it with Nonce
+	ContentSecurityPolicy *ContentSecurityPolicy
+
+	// PermissionsPolicy sets the `Permissions-Policy` header from the features and the origins allowed to use them,
+	// self and * can be used as origins and a feature without origins is disabled, so
+	// {"camera": {"self"}, "geolocation": {}} sends `camera=(self), geolocation=()`
+	// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Permissions-Policy
+	PermissionsPolicy map[string][]string
+
+	// CrossOriginOpenerPolicy sets the `Cross-Origin-Opener-Policy` header, like same-origin
+	CrossOriginOpenerPolicy string
+	// CrossOriginEmbedderPolicy sets the `Cross-Origin-Embedder-Policy` header, like require-corp
+	CrossOriginEmbedderPolicy string
+	// CrossOriginResourcePolicy sets the `Cross-Origin-Resource-Policy` header, like same-site
+	CrossOriginResourcePolicy string
+	// CrossOriginReportOnly sends the opener and embedder policies as `Cross-Origin-Opener-Policy-Report-Only` and
+	// `Cross-Origin-Embedder-Policy-Report-Only`, so violations are reported but not blocked
+	CrossOriginReportOnly bool
 }
 
 // SetDefaultHeaders will set certain default headers specified by the user
@@ -77,8 +108,25 @@ func (defaultHeaders *DefaultHeaders) SetDefaultHeaders(h http.HandlerFunc) http
 	}
 }
 
-// SetSecureHeaders sets some default security headers
+// SetSecureHeaders sets some default security headers, the headers which do not depend on the request are built
+// when it is called
 func (secureHeaders SecureHeaders) SetSecureHeaders(h http.HandlerFunc) http.HandlerFunc {
+	hsts := secureHeaders.hsts()
+	permissionsPolicy := secureHeaders.permissionsPolicy()
+
+	// The policy is split at the nonces, so only the nonce has to be filled in for every request
+	var cspName string
+	var cspParts []string
+	if secureHeaders.ContentSecurityPolicy != nil {
+		cspName = secureHeaders.ContentSecurityPolicy.headerName()
+		cspParts = strings.Split(secureHeaders.ContentSecurityPolicy.String(), SourceNonce)
+	}
+
+	openerName, embedderName := "Cross-Origin-Opener-Policy", "Cross-Origin-Embedder-Policy"
+	if secureHeaders.CrossOriginReportOnly {
+		openerName, embedderName = openerName+"-Report-Only", embedderName+"-Report-Only"
+	}
+
 	return func(w http.ResponseWriter, request *http.Request) {
 		if secureHeaders.XSSProtection != "" {
 			w.Header().Set("X-XSS-Protection", secureHeaders.XSSProtection)
@@ -99,7 +147,105 @@ func (secureHeaders SecureHeaders) SetSecureHeaders(h http.HandlerFunc) http.Han
 			w.Header().Set("X-Frame-Options", "deny")
 		}
 
+		if hsts != "" && secureHeaders.isHTTPS(request) {
+			w.Header().Set("Strict-Transport-Security", hsts)
+		}
+
 		w.Header().Set("Referrer-Policy", secureHeaders.ReferrerPolicy)
+
+		if cspParts != nil {
+			policy := cspParts[0]
+			if len(cspParts) > 1 {
+				nonce, err := newNonce()
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+
+				policy = strings.Join(cspParts, "'nonce-"+nonce+"'")
+				request = request.WithContext(context.WithValue(request.Context(), nonceKey{}, nonce))
+			}
+
+			w.Header().Set(cspName, policy)
+		}
+
+		if permissionsPolicy != "" {
+			w.Header().Set("Permissions-Policy", permissionsPolicy)
+		}
+
+		if secureHeaders.CrossOriginOpenerPolicy != "" {
+			w.Header().Set(openerName, secureHeaders.CrossOriginOpenerPolicy)
+		}
+
+		if secureHeaders.CrossOriginEmbedderPolicy != "" {
+			w.Header().Set(embedderName, secureHeaders.CrossOriginEmbedderPolicy)
+		}
+
+		if secureHeaders.CrossOriginResourcePolicy != "" {
+			w.Header().Set("Cross-Origin-Resource-Policy", secureHeaders.CrossOriginResourcePolicy)
+		}
+
 		h.ServeHTTP(w, request)
 	}
 }
+
+// hsts returns the value of the Strict-Transport-Security header, it is empty when HSTSMaxAge is not set
+func (secureHeaders SecureHeaders) hsts() string {
+	if secureHeaders.HSTSMaxAge <= 0 {
+		return ""
+	}
+
+	hsts := "max-age=" + strconv.Itoa(secureHeaders.HSTSMaxAge)
+
+	if secureHeaders.HSTSIncludeSubdomains {
+		hsts += "; includeSubDomains"
+	}
+
+	if secureHeaders.HSTSPreload {
+		hsts += "; preload"
+	}
+
+	return hsts
+}
+
+// isHTTPS tells if the request was made over TLS, browsers ignore Strict-Transport-Security on other requests
+func (secureHeaders SecureHeaders) isHTTPS(request *http.Request) bool {
+	if request.TLS != nil {
+		return true
+	}
+
+	return secureHeaders.HSTSTrustForwardedProto && strings.EqualFold(request.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// permissionsPolicy returns the value of the Permissions-Policy header, with the features in alphabetical order
+func (secureHeaders SecureHeaders) permissionsPolicy() string {
+	features := make([]string, 0, len(secureHeaders.PermissionsPolicy))
+	for feature := range secureHeaders.PermissionsPolicy {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	directives := make([]string, 0, len(features))
+	for _, feature := range features {
+		origins := make([]string, 0, len(secureHeaders.PermissionsPolicy[feature]))
+		for _, origin := range secureHeaders.PermissionsPolicy[feature] {
+			switch origin {
+			case "*":
+				origins = append(origins, origin)
+			case "self", "'self'":
+				origins = append(origins, "self")
+			default:
+				origins = append(origins, strconv.Quote(origin))
+			}
+		}
+
+		// * allows every origin and is sent without parentheses
+		if len(origins) == 1 && origins[0] == "*" {
+			directives = append(directives, feature+"=*")
+		} else {
+			directives = append(directives, feature+"=("+strings.Join(origins, " ")+")")
+		}
+	}
+
+	return strings.Join(directives, ", ")
+}
@@ -1,7 +1,9 @@
-package middleware
+package middleware_test
 
 import (
+	"crypto/tls"
 	"fmt"
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
 	"github.com/flannel-dev-lab/cyclops/v2/router"
 	"net/http"
 	"net/http/httptest"
@@ -9,17 +11,17 @@ import (
 )
 
 func TestSecureHeaders_SetSecureHeaders(t *testing.T) {
-	cases := []SecureHeaders{
-		{"1; mode=block", "nosniff", "sameorigin", ""},
-		{"", "nosniff", "sameorigin", ""},
-		{"1; mode=block", "", "sameorigin", ""},
-		{"1; mode=block", "nosniff", "", ""},
+	cases := []middleware.SecureHeaders{
+		{XSSProtection: "1; mode=block", ContentTypeOptions: "nosniff", FrameOptions: "sameorigin"},
+		{ContentTypeOptions: "nosniff", FrameOptions: "sameorigin"},
+		{XSSProtection: "1; mode=block", FrameOptions: "sameorigin"},
+		{XSSProtection: "1; mode=block", ContentTypeOptions: "nosniff"},
 	}
 
 	for _, testCase := range cases {
 
 		r := router.New(true, nil, nil)
-		r.Post("/use", NewChain(testCase.SetSecureHeaders).Then(func(w http.ResponseWriter, r *http.Request) {}))
+		r.Post("/use", middleware.NewChain(testCase.SetSecureHeaders).Then(func(w http.ResponseWriter, r *http.Request) {}))
 
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest("POST", "/use", nil)
@@ -39,7 +41,7 @@ func TestSecureHeaders_SetSecureHeaders(t *testing.T) {
 }
 
 func TestDefaultHeaders_SetDefaultHeaders(t *testing.T) {
-	cases := []DefaultHeaders{
+	cases := []middleware.DefaultHeaders{
 		{"test", []string{"en"}, "application/json", "Wed, 21 Oct 2015 07:28:00 GMT", "Mon"},
 		{"", []string{"en"}, "application/json", "Wed, 21 Oct 2015 07:28:00 GMT", "Mon"},
 		{"test", []string{"en"}, "", "Wed, 21 Oct 2015 07:28:00 GMT", "Mon"},
@@ -48,7 +50,7 @@ func TestDefaultHeaders_SetDefaultHeaders(t *testing.T) {
 	for _, testCase := range cases {
 
 		r := router.New(true, nil, nil)
-		r.Post("/use", NewChain(testCase.SetDefaultHeaders).Then(func(w http.ResponseWriter, r *http.Request) {}))
+		r.Post("/use", middleware.NewChain(testCase.SetDefaultHeaders).Then(func(w http.ResponseWriter, r *http.Request) {}))
 
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest("POST", "/use", nil)
@@ -65,3 +67,94 @@ func TestDefaultHeaders_SetDefaultHeaders(t *testing.T) {
 
 	}
 }
+
+func TestSecureHeaders_HSTS(t *testing.T) {
+	secureHeaders := middleware.SecureHeaders{
+		HSTSMaxAge:              31536000,
+		HSTSIncludeSubdomains:   true,
+		HSTSPreload:             true,
+		HSTSTrustForwardedProto: true,
+	}
+
+	handler := middleware.NewPlainChain(secureHeaders.SetSecureHeaders).Then(func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		TLS            bool
+		ForwardedProto string
+		Expected       string
+	}{
+		{true, "", "max-age=31536000; includeSubDomains; preload"},
+		{false, "https", "max-age=31536000; includeSubDomains; preload"},
+		{false, "http", ""},
+		{false, "", ""},
+	}
+
+	for _, testCase := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if testCase.TLS {
+			req.TLS = &tls.ConnectionState{}
+		}
+		if testCase.ForwardedProto != "" {
+			req.Header.Set("X-Forwarded-Proto", testCase.ForwardedProto)
+		}
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		if w.Header().Get("Strict-Transport-Security") != testCase.Expected {
+			t.Errorf("%s: %+v: expected %q got %q", t.Name(), testCase, testCase.Expected, w.Header().Get("Strict-Transport-Security"))
+		}
+	}
+}
+
+func TestSecureHeaders_Policies(t *testing.T) {
+	cases := []struct {
+		SecureHeaders middleware.SecureHeaders
+		Expected      map[string]string
+	}{
+		{
+			middleware.SecureHeaders{
+				PermissionsPolicy: map[string][]string{
+					"geolocation": {},
+					"camera":      {"self", "https://meet.example.com"},
+					"fullscreen":  {"*"},
+				},
+				CrossOriginOpenerPolicy:   "same-origin",
+				CrossOriginEmbedderPolicy: "require-corp",
+				CrossOriginResourcePolicy: "same-site",
+			},
+			map[string]string{
+				"Permissions-Policy":           `camera=(self "https://meet.example.com"), fullscreen=*, geolocation=()`,
+				"Cross-Origin-Opener-Policy":   "same-origin",
+				"Cross-Origin-Embedder-Policy": "require-corp",
+				"Cross-Origin-Resource-Policy": "same-site",
+				"Strict-Transport-Security":    "",
+			},
+		},
+		{
+			middleware.SecureHeaders{
+				CrossOriginOpenerPolicy:   "same-origin",
+				CrossOriginEmbedderPolicy: "require-corp",
+				CrossOriginReportOnly:     true,
+			},
+			map[string]string{
+				"Cross-Origin-Opener-Policy-Report-Only":   "same-origin",
+				"Cross-Origin-Embedder-Policy-Report-Only": "require-corp",
+				"Cross-Origin-Opener-Policy":               "",
+				"Permissions-Policy":                       "",
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		w := httptest.NewRecorder()
+
+		middleware.NewPlainChain(testCase.SecureHeaders.SetSecureHeaders).Then(func(w http.ResponseWriter, r *http.Request) {})(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		for header, expected := range testCase.Expected {
+			if w.Header().Get(header) != expected {
+				t.Errorf("%s: %s: expected %q got %q", t.Name(), header, expected, w.Header().Get(header))
+			}
+		}
+	}
+}
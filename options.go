@@ -0,0 +1,60 @@
+package cyclops
+
+import (
+	"net/http"
+	"time"
+)
+
+// Default limits of a Server, they keep slow clients from holding connections open indefinitely
+const (
+	DefaultReadTimeout       = 30 * time.Second
+	DefaultReadHeaderTimeout = 10 * time.Second
+	DefaultWriteTimeout      = 30 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultMaxHeaderBytes    = http.DefaultMaxHeaderBytes
+)
+
+// Options configures the limits of a Server. A zero value uses the default limit and a negative one removes the
+// limit, so Options{WriteTimeout: -1} keeps the other defaults but lets handlers stream responses for as long as they
+// need
+type Options struct {
+	// ReadTimeout is the maximum duration for reading an entire request, including the body
+	ReadTimeout time.Duration
+	// ReadHeaderTimeout is the maximum duration for reading the headers of a request
+	ReadHeaderTimeout time.Duration
+	// WriteTimeout is the maximum duration from the end of reading the headers of a request to the end of writing
+	// its response
+	WriteTimeout time.Duration
+	// IdleTimeout is the maximum duration to wait for the next request on a keep-alive connection
+	IdleTimeout time.Duration
+	// MaxHeaderBytes is the maximum size of the headers of a request, including the request line. It can not be
+	// removed, a negative value uses the default as well
+	MaxHeaderBytes int
+	// HideBanner stops the server from printing the banner when it starts
+	HideBanner bool
+}
+
+// apply sets the limits of the options on server
+func (o Options) apply(server *http.Server) {
+	server.ReadTimeout = duration(o.ReadTimeout, DefaultReadTimeout)
+	server.ReadHeaderTimeout = duration(o.ReadHeaderTimeout, DefaultReadHeaderTimeout)
+	server.WriteTimeout = duration(o.WriteTimeout, DefaultWriteTimeout)
+	server.IdleTimeout = duration(o.IdleTimeout, DefaultIdleTimeout)
+
+	server.MaxHeaderBytes = DefaultMaxHeaderBytes
+	if o.MaxHeaderBytes > 0 {
+		server.MaxHeaderBytes = o.MaxHeaderBytes
+	}
+}
+
+// duration returns the default for a zero limit and no limit for a negative one
+func duration(limit, defaultLimit time.Duration) time.Duration {
+	switch {
+	case limit == 0:
+		return defaultLimit
+	case limit < 0:
+		return 0
+	default:
+		return limit
+	}
+}
@@ -0,0 +1,47 @@
+package router
+
+import (
+	"regexp"
+	"strings"
+)
+
+// constraints contains the named constraints that can be used instead of a regular expression, as in /users/:id<int>
+var constraints = map[string]*regexp.Regexp{
+	"int":   regexp.MustCompile(`^-?[0-9]+$`),
+	"uint":  regexp.MustCompile(`^[0-9]+$`),
+	"alpha": regexp.MustCompile(`^[a-zA-Z]+$`),
+	"alnum": regexp.MustCompile(`^[a-zA-Z0-9]+$`),
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+}
+
+// parseParam splits a named or catch-all component like :id<int> into the name of the param and its constraint,
+// the constraint is empty when the param is unconstrained
+func parseParam(component string) (name, constraint string) {
+	name = component[1:]
+
+	if idx := strings.IndexByte(name, '<'); idx != -1 && strings.HasSuffix(name, ">") {
+		return name[:idx], name[idx+1 : len(name)-1]
+	}
+
+	return name, ""
+}
+
+// compileConstraint returns the expression a constrained param must match, it is either one of the named
+// constraints or a regular expression which has to match the whole component. It panics when the regular
+// expression is invalid
+func compileConstraint(constraint, path string) *regexp.Regexp {
+	if constraint == "" {
+		return nil
+	}
+
+	if expression, ok := constraints[constraint]; ok {
+		return expression
+	}
+
+	expression, err := regexp.Compile("^(?:" + constraint + ")$")
+	if err != nil {
+		panic("invalid constraint '" + constraint + "' in path '" + path + "': " + err.Error())
+	}
+
+	return expression
+}
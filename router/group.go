@@ -0,0 +1,125 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
+)
+
+// Group is a set of routes that share a path prefix and a middleware chain
+type Group struct {
+	router *Router
+	parent *Group
+	prefix string
+	// middlewares contains the middleware of this group only, the middleware of the parent groups is added when a
+	// handler is registered
+	middlewares []middleware.Middlewares
+	// plain tells if the handlers are wrapped without the default middleware of middleware.NewChain
+	plain bool
+}
+
+// Group creates a route group, every handler registered on the group is served under prefix and wrapped with mws and
+// the default middleware of middleware.NewChain
+func (r *Router) Group(prefix string, mws ...middleware.Middlewares) *Group {
+	return &Group{router: r, prefix: cleanPrefix(prefix), middlewares: mws}
+}
+
+// PlainGroup is like Group but the handlers are only wrapped with mws, like with middleware.NewPlainChain. It is used
+// for handlers that bring their own chain, so they are not logged twice
+func (r *Router) PlainGroup(prefix string, mws ...middleware.Middlewares) *Group {
+	return &Group{router: r, prefix: cleanPrefix(prefix), middlewares: mws, plain: true}
+}
+
+// Group creates a nested route group, the prefix is appended to the prefix of the parent group and the middleware of
+// the parent groups is inherited. The nested group of a plain group is plain too
+func (g *Group) Group(prefix string, mws ...middleware.Middlewares) *Group {
+	return &Group{router: g.router, parent: g, prefix: g.prefix + cleanPrefix(prefix), middlewares: mws, plain: g.plain}
+}
+
+// Get - Helper method to add HTTP GET Method to group
+func (g *Group) Get(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodGet, path, handler)
+}
+
+// Post - Helper method to add HTTP POST Method to group
+func (g *Group) Post(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodPost, path, handler)
+}
+
+// Connect - Helper method to add HTTP CONNECT Method to group
+func (g *Group) Connect(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodConnect, path, handler)
+}
+
+// Delete - Helper method to add HTTP DELETE Method to group
+func (g *Group) Delete(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodDelete, path, handler)
+}
+
+// Patch - Helper method to add HTTP PATCH Method to group
+func (g *Group) Patch(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodPatch, path, handler)
+}
+
+// Put - Helper method to add HTTP PUT Method to group
+func (g *Group) Put(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodPut, path, handler)
+}
+
+// Trace - Helper method to add HTTP TRACE Method to group
+func (g *Group) Trace(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodTrace, path, handler)
+}
+
+// Head - Helper method to add HTTP HEAD Method to group
+func (g *Group) Head(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodHead, path, handler)
+}
+
+// Options - Helper method to add HTTP OPTIONS Method to group
+func (g *Group) Options(path string, handler http.HandlerFunc) *Route {
+	return g.add(http.MethodOptions, path, handler)
+}
+
+func (g *Group) add(method, path string, handler http.HandlerFunc) *Route {
+	if len(path) < 1 || path[0] != '/' {
+		panic("path must begin with '/' in path '" + path + "'")
+	}
+
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+
+	mws := g.chain()
+
+	chain := middleware.NewChain(mws...)
+	if g.plain {
+		chain = middleware.NewPlainChain(mws...)
+	}
+
+	route := g.router.add(method, g.prefix+path, chain.Then(handler))
+	route.middlewares = len(mws)
+
+	return route
+}
+
+// chain returns the middleware of the group followed by the middleware of its parents, middleware's are chained in
+// the order they are specified so the middleware of a parent group wraps, and runs before, that of its children
+func (g *Group) chain() []middleware.Middlewares {
+	var mws []middleware.Middlewares
+	for group := g; group != nil; group = group.parent {
+		mws = append(mws, group.middlewares...)
+	}
+
+	return mws
+}
+
+// cleanPrefix makes sure a group prefix begins with a '/' and does not end with one
+func cleanPrefix(prefix string) string {
+	if len(prefix) < 1 || prefix[0] != '/' {
+		panic("prefix must begin with '/' in prefix '" + prefix + "'")
+	}
+
+	return strings.TrimSuffix(prefix, "/")
+}
@@ -0,0 +1,106 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func trace(name string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s>", name)
+			h(w, r)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	r := New(true, nil, nil)
+
+	api := r.Group("/api/v1", trace("api"))
+	api.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "users")
+	})
+
+	admin := api.Group("/admin/", trace("admin"), trace("audit"))
+	admin.Post("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "admin %s", ParamsFromContext(r.Context()).Get("id"))
+	})
+
+	cases := []struct {
+		Method string
+		Path   string
+		Code   int
+		Body   string
+	}{
+		{http.MethodGet, "/api/v1/users", http.StatusOK, "api>users"},
+		{http.MethodPost, "/api/v1/admin/users/1", http.StatusOK, "api>audit>admin>admin 1"},
+		{http.MethodGet, "/users", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/v1/admin/users/1", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s", t.Name(), testCase.Code, w.Code, testCase.Path)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Body, w.Body.String(), testCase.Path)
+		}
+	}
+}
+
+func TestGroup_Plain(t *testing.T) {
+	r := New(true, nil, nil)
+
+	panics := func(w http.ResponseWriter, r *http.Request) {
+		panic("handler panic")
+	}
+
+	r.Group("/default").Get("/panic", panics)
+	plain := r.PlainGroup("/plain", trace("plain"))
+	plain.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "users")
+	})
+	plain.Group("/nested").Get("/panic", panics)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/default/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("%s: expected the default PanicHandler to answer %d got %d", t.Name(), http.StatusInternalServerError, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plain/users", nil))
+
+	if w.Body.String() != "plain>users" {
+		t.Errorf("%s: expected 'plain>users' got '%s'", t.Name(), w.Body.String())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: expected a nested plain group to not recover the panic", t.Name())
+		}
+	}()
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/plain/nested/panic", nil))
+}
+
+func TestGroup_IncorrectPrefix(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: should have panicked", t.Name())
+		}
+	}()
+
+	r := New(true, nil, nil)
+	r.Group("api")
+}
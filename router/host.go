@@ -0,0 +1,127 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Hosts dispatches requests to the handler, usually a *Router, registered for the host of the request
+type Hosts struct {
+	// exact contains the handlers of host patterns without params
+	exact map[string]http.Handler
+	// patterns contains the host patterns with params, ordered so the patterns with the most literal labels are
+	// matched first
+	patterns []hostPattern
+	// Fallback handles requests for hosts without a handler, the NotFoundHandler of cyclops is used when it is nil
+	Fallback http.Handler
+}
+
+// hostPattern is a host pattern split into its labels
+type hostPattern struct {
+	labels   []string
+	literals int
+	handler  http.Handler
+}
+
+// NewHosts creates a Hosts that hands requests for unknown hosts to fallback
+func NewHosts(fallback http.Handler) *Hosts {
+	return &Hosts{exact: make(map[string]http.Handler), Fallback: fallback}
+}
+
+// Handle registers handler for a host pattern like api.example.com, labels beginning with ':' capture the label
+// as a param, so :tenant.example.com serves acme.example.com with the param tenant set to acme. Hosts are matched
+// without regard to case and port
+func (h *Hosts) Handle(pattern string, handler http.Handler) {
+	if pattern == "" {
+		panic("host pattern must not be empty")
+	}
+
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+
+	// Hosts are matched without regard to case, so only the literal labels are lower cased and the params keep the
+	// names they are read with
+	labels := strings.Split(pattern, ".")
+
+	literals := 0
+	for idx, label := range labels {
+		if len(label) > 0 && label[0] == ':' {
+			if len(label) < 2 {
+				panic("host param must be named in pattern '" + pattern + "'")
+			}
+			continue
+		}
+		labels[idx] = strings.ToLower(label)
+		literals++
+	}
+	pattern = strings.Join(labels, ".")
+
+	if literals == len(labels) {
+		h.exact[pattern] = handler
+		return
+	}
+
+	for idx, existing := range h.patterns {
+		if strings.Join(existing.labels, ".") == pattern {
+			h.patterns[idx].handler = handler
+			return
+		}
+	}
+
+	h.patterns = append(h.patterns, hostPattern{labels: labels, literals: literals, handler: handler})
+	sort.SliceStable(h.patterns, func(i, j int) bool {
+		return h.patterns[i].literals > h.patterns[j].literals
+	})
+}
+
+func (h *Hosts) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	host := strings.ToLower(req.Host)
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(host, ".")
+
+	if handler, ok := h.exact[host]; ok {
+		handler.ServeHTTP(w, req)
+		return
+	}
+
+	labels := strings.Split(host, ".")
+	for _, pattern := range h.patterns {
+		if params, ok := pattern.match(labels); ok {
+			params = append(copyParams(ParamsFromContext(req.Context()), len(params)), params...)
+			pattern.handler.ServeHTTP(w, req.WithContext(withParams(req.Context(), params)))
+			return
+		}
+	}
+
+	if h.Fallback != nil {
+		h.Fallback.ServeHTTP(w, req)
+	} else {
+		CyclopsNotFoundHandler(w, req)
+	}
+}
+
+// match tells if the host labels match the pattern and returns the params captured from them
+func (p hostPattern) match(labels []string) (Params, bool) {
+	if len(labels) != len(p.labels) {
+		return nil, false
+	}
+
+	var params Params
+	for idx, label := range p.labels {
+		if len(label) > 0 && label[0] == ':' {
+			if labels[idx] == "" {
+				return nil, false
+			}
+			params = append(params, Param{Key: label[1:], Value: labels[idx]})
+		} else if label != labels[idx] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
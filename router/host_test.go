@@ -0,0 +1,95 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHosts(t *testing.T) {
+	api := New(false, nil, nil)
+	api.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "api %s", ParamsFromContext(r.Context()).Get("id"))
+	})
+
+	tenants := New(false, nil, nil)
+	tenants.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		params := ParamsFromContext(r.Context())
+		fmt.Fprintf(w, "%s %s", params.Get("tenant"), params.Get("id"))
+	})
+	tenants.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s home", ParamsFromContext(r.Context()).Get("tenant"))
+	})
+
+	regions := New(false, nil, nil)
+	regions.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		params := ParamsFromContext(r.Context())
+		fmt.Fprintf(w, "%s in %s", params.Get("tenant"), params.Get("region"))
+	})
+
+	hosts := NewHosts(nil)
+	hosts.Handle(":tenant.:region.example.com", regions)
+	hosts.Handle(":tenant.example.com", tenants)
+	hosts.Handle("API.example.com", api)
+
+	cases := []struct {
+		Host string
+		Path string
+		Code int
+		Body string
+	}{
+		{"api.example.com", "/users/1", http.StatusOK, "api 1"},
+		{"Api.Example.com:8080", "/users/1", http.StatusOK, "api 1"},
+		{"acme.example.com", "/users/1", http.StatusOK, "acme 1"},
+		{"acme.example.com.", "/", http.StatusOK, "acme home"},
+		{"acme.eu.example.com", "/", http.StatusOK, "acme in eu"},
+		{"example.com", "/", http.StatusNotFound, ""},
+		{"acme.example.org", "/", http.StatusNotFound, ""},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest("GET", testCase.Path, nil)
+		req.Host = testCase.Host
+		w := httptest.NewRecorder()
+
+		hosts.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s", t.Name(), testCase.Code, w.Code, testCase.Host)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Body, w.Body.String(), testCase.Host)
+		}
+	}
+
+	hosts.Fallback = http.RedirectHandler("https://www.example.com", http.StatusFound)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Host = "example.com"
+	w := httptest.NewRecorder()
+
+	hosts.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Errorf("%s: expected fallback to be used, got %d", t.Name(), w.Code)
+	}
+}
+
+func TestHosts_ParamNameCase(t *testing.T) {
+	hosts := NewHosts(nil)
+	hosts.Handle(":tenantID.Example.COM", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "tenant %s", ParamsFromContext(r.Context()).Get("tenantID"))
+	}))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Host = "Acme.example.com"
+	w := httptest.NewRecorder()
+
+	hosts.ServeHTTP(w, req)
+
+	if w.Body.String() != "tenant acme" {
+		t.Errorf("%s: expected %q got %q", t.Name(), "tenant acme", w.Body.String())
+	}
+}
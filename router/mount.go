@@ -0,0 +1,80 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Handle registers a http.Handler for the method and path
+func (r *Router) Handle(method, path string, handler http.Handler) *Route {
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+
+	return r.add(method, path, handler.ServeHTTP)
+}
+
+// Handle registers a http.Handler for the method and path on the group
+func (g *Group) Handle(method, path string, handler http.Handler) *Route {
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+
+	return g.add(method, path, handler.ServeHTTP)
+}
+
+// Mount hands every request for prefix and the paths below it to handler, whatever the method, with prefix
+// stripped from the path. The prefix can contain named parameters, they are available to handler as params. Routes
+// registered on the router below prefix take priority over the mounted handler, for every method: a request for the
+// path of such a route with a method it has no handler for is answered with 405 Method Not Allowed and does not reach
+// the mounted handler
+func (r *Router) Mount(prefix string, handler http.Handler) {
+	if handler == nil {
+		panic("handler must not be nil")
+	}
+
+	prefix = cleanPrefix(prefix)
+	mounted := stripSegments(strings.Count(prefix, "/"), handler)
+
+	if prefix != "" {
+		r.add(anyMethod, prefix, mounted)
+	}
+	r.register(anyMethod, prefix+"/*", mounted, true)
+}
+
+// stripSegments returns a handler that removes the first count segments from the path of the request before it
+// hands the request to handler
+func stripSegments(count int, handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		u := new(url.URL)
+		*u = *req.URL
+		u.Path = trimSegments(req.URL.Path, count)
+		if req.URL.RawPath != "" {
+			u.RawPath = trimSegments(req.URL.RawPath, count)
+		}
+
+		r := new(http.Request)
+		*r = *req
+		r.URL = u
+
+		handler.ServeHTTP(w, r)
+	}
+}
+
+// trimSegments removes the first count segments from path, it returns "/" when no path is left
+func trimSegments(path string, count int) string {
+	for i := 0; i < count && path != ""; i++ {
+		if idx := strings.IndexByte(path[1:], '/'); idx != -1 {
+			path = path[idx+1:]
+		} else {
+			path = ""
+		}
+	}
+
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
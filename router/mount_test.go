@@ -0,0 +1,69 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter_Mount(t *testing.T) {
+	debug := http.NewServeMux()
+	debug.HandleFunc("/pprof/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "pprof %s %s", r.Method, r.URL.Path)
+	})
+
+	tenant := New(false, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}, nil)
+	tenant.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "tenant %s home", ParamsFromContext(r.Context()).Get("tenant"))
+	})
+	tenant.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		params := ParamsFromContext(r.Context())
+		fmt.Fprintf(w, "tenant %s user %s", params.Get("tenant"), params.Get("id"))
+	})
+
+	r := New(false, nil, nil)
+	r.Mount("/debug/", debug)
+	r.Mount("/tenants/:tenant", tenant)
+	r.Get("/debug/health", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "healthy")
+	})
+	r.Handle(http.MethodGet, "/handler", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "handler")
+	}))
+
+	cases := []struct {
+		Method string
+		Path   string
+		Code   int
+		Body   string
+	}{
+		{http.MethodGet, "/debug/pprof/heap", http.StatusOK, "pprof GET /pprof/heap"},
+		{http.MethodPost, "/debug/pprof/", http.StatusOK, "pprof POST /pprof/"},
+		{http.MethodGet, "/debug/health", http.StatusOK, "healthy"},
+		{http.MethodPost, "/debug/health", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/debug/missing", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodGet, "/tenants/acme", http.StatusOK, "tenant acme home"},
+		{http.MethodGet, "/tenants/acme/users/1", http.StatusOK, "tenant acme user 1"},
+		{http.MethodGet, "/tenants/acme/missing", http.StatusTeapot, ""},
+		{http.MethodGet, "/handler", http.StatusOK, "handler"},
+		{http.MethodGet, "/tenants", http.StatusNotFound, ""},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s %s", t.Name(), testCase.Code, w.Code, testCase.Method, testCase.Path)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s %s", t.Name(), testCase.Body, w.Body.String(), testCase.Method, testCase.Path)
+		}
+	}
+}
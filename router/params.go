@@ -0,0 +1,52 @@
+package router
+
+import (
+	"context"
+)
+
+// Param is a single URL parameter, consisting of a key and a value
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params is a Param-slice holding the named and catch-all parameters of a matched route in the order they appear
+// in the path
+type Params []Param
+
+// Get returns the value of the first Param which key matches the given name, an empty string is returned if no
+// matching Param is found
+func (ps Params) Get(name string) string {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value
+		}
+	}
+
+	return ""
+}
+
+// paramsKey is the context key the params of a request are stored under
+type paramsKey struct{}
+
+// ParamsFromContext returns the params the router stored in the request context, it returns nil when the matched
+// route has no params. The router reuses the params once the handler returns, copy them to use them afterwards, for
+// instance in a goroutine started by the handler
+func ParamsFromContext(ctx context.Context) Params {
+	params, _ := ctx.Value(paramsKey{}).(Params)
+	return params
+}
+
+// withParams returns a copy of ctx that holds params
+func withParams(ctx context.Context, params Params) context.Context {
+	return context.WithValue(ctx, paramsKey{}, params)
+}
+
+// copyParams returns a copy of params with room for extra params, so appending to it does not modify params
+func copyParams(params Params, extra int) Params {
+	if len(params) == 0 {
+		return nil
+	}
+
+	return append(make(Params, 0, len(params)+extra), params...)
+}
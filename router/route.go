@@ -0,0 +1,150 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Route is a handler registered on the router for a method and path pattern
+type Route struct {
+	router *Router
+	// Method is the HTTP method the route is registered for
+	Method string
+	// Pattern is the path the route is registered on, including group prefixes
+	Pattern string
+	// name is the name the route can be looked up with to build its URL
+	name string
+	// middlewares is the number of group middleware the handler was wrapped with when it was registered
+	middlewares int
+}
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	// Method is the HTTP method of the route, it is * for the routes of a handler registered with Mount
+	Method  string
+	Pattern string
+	Name    string
+	// Middlewares is the number of group middleware wrapping the handler, middleware registered with Router.Use or
+	// chained by the handler itself is not counted
+	Middlewares int
+}
+
+// Name sets the name of the route so its URL can be built with Router.URL, names must be unique within a router
+func (route *Route) Name(name string) *Route {
+	if name == "" {
+		panic("route name must not be empty")
+	}
+
+	if existing, ok := route.router.named[name]; ok && existing != route {
+		panic("route name '" + name + "' is already used by '" + existing.Pattern + "'")
+	}
+
+	if route.name != "" {
+		delete(route.router.named, route.name)
+	}
+
+	route.name = name
+	route.router.named[name] = route
+
+	return route
+}
+
+// URL builds the path of the route registered with name, the params are given as key value pairs and are escaped
+// before they are placed in the path. An error is returned when there is no route with the name or a param of the
+// route is not given or does not match its constraint
+func (r *Router) URL(name string, pairs ...string) (string, error) {
+	route, ok := r.named[name]
+	if !ok {
+		return "", errors.New("route '" + name + "' not found")
+	}
+
+	if len(pairs)%2 != 0 {
+		return "", errors.New("params for route '" + name + "' must be given as key value pairs")
+	}
+
+	params := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		params[pairs[i]] = pairs[i+1]
+	}
+
+	components := strings.Split(route.Pattern, "/")
+	for idx, component := range components {
+		if len(component) < 1 || (component[0] != ':' && component[0] != '*') {
+			continue
+		}
+
+		// An unnamed catch-all, as registered by Mount, is left empty
+		if component == "*" {
+			components[idx] = ""
+			continue
+		}
+
+		key, constraint := parseParam(component)
+
+		value, ok := params[key]
+		if !ok || (value == "" && component[0] == ':') {
+			return "", errors.New("missing param '" + key + "' for route '" + name + "'")
+		}
+
+		if expression := compileConstraint(constraint, route.Pattern); expression != nil && !expression.MatchString(value) {
+			return "", errors.New("param '" + key + "' for route '" + name + "' does not match '" + constraint + "'")
+		}
+
+		// A catch-all spans several components, so only the components of its value are escaped
+		if component[0] == '*' {
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			components[idx] = strings.Join(segments, "/")
+		} else {
+			components[idx] = url.PathEscape(value)
+		}
+	}
+
+	return strings.Join(components, "/"), nil
+}
+
+// Routes returns every registered route sorted by pattern and method
+func (r *Router) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, RouteInfo{
+			Method:      route.Method,
+			Pattern:     route.Pattern,
+			Name:        route.name,
+			Middlewares: route.middlewares,
+		})
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// PrintRoutes writes a table of the registered routes sorted by pattern and method to w
+func (r *Router) PrintRoutes(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if _, err := fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tMIDDLEWARES"); err != nil {
+		return err
+	}
+
+	for _, route := range r.Routes() {
+		if _, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", route.Method, route.Pattern, route.Name, route.Middlewares); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
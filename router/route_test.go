@@ -0,0 +1,95 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRouter_URL(t *testing.T) {
+	r := New(true, nil, nil)
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Name("user.show")
+	r.Get("/files/*path", func(w http.ResponseWriter, r *http.Request) {}).Name("file.show")
+	r.Get("/posts/:id<int>", func(w http.ResponseWriter, r *http.Request) {}).Name("post.show")
+	r.Group("/api/v1").Post("/users/:id/posts/", func(w http.ResponseWriter, r *http.Request) {}).Name("post.create")
+
+	cases := []struct {
+		Name  string
+		Pairs []string
+		URL   string
+		Error bool
+	}{
+		{"user.show", []string{"id", "42"}, "/users/42", false},
+		{"user.show", []string{"id", "a b/c"}, "/users/a%20b%2Fc", false},
+		{"file.show", []string{"path", "css/site main.css"}, "/files/css/site%20main.css", false},
+		{"post.create", []string{"id", "42", "unused", "1"}, "/api/v1/users/42/posts", false},
+		{"user.show", nil, "", true},
+		{"user.show", []string{"id", ""}, "", true},
+		{"user.show", []string{"id"}, "", true},
+		{"user.missing", []string{"id", "42"}, "", true},
+		{"post.show", []string{"id", "42"}, "/posts/42", false},
+		{"post.show", []string{"id", "abc"}, "", true},
+	}
+
+	for _, testCase := range cases {
+		url, err := r.URL(testCase.Name, testCase.Pairs...)
+		if (err != nil) != testCase.Error {
+			t.Errorf("%s: expected error %t got %v for %s %v", t.Name(), testCase.Error, err, testCase.Name, testCase.Pairs)
+		}
+
+		if url != testCase.URL {
+			t.Errorf("%s: expected '%s' got '%s' for %s %v", t.Name(), testCase.URL, url, testCase.Name, testCase.Pairs)
+		}
+	}
+}
+
+func TestRoute_DuplicateName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: should have panicked", t.Name())
+		}
+	}()
+
+	r := New(true, nil, nil)
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Name("user")
+	r.Delete("/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Name("user")
+}
+
+func TestRouter_Routes(t *testing.T) {
+	r := New(true, nil, nil)
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {}).Name("user.show")
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {}).Name("user.list")
+	r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+	admin := r.Group("/admin", trace("admin")).Group("/audit", trace("audit"))
+	admin.Delete("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+
+	expected := []RouteInfo{
+		{http.MethodDelete, "/admin/audit/users/:id", "", 2},
+		{http.MethodGet, "/users", "user.list", 0},
+		{http.MethodPost, "/users", "", 0},
+		{http.MethodGet, "/users/:id", "user.show", 0},
+	}
+
+	routes := r.Routes()
+	if len(routes) != len(expected) {
+		t.Fatalf("%s: expected %d routes got %d", t.Name(), len(expected), len(routes))
+	}
+
+	for idx, route := range routes {
+		if route != expected[idx] {
+			t.Errorf("%s: expected %v got %v", t.Name(), expected[idx], route)
+		}
+	}
+
+	var table strings.Builder
+	if err := r.PrintRoutes(&table); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != len(expected)+1 || !strings.HasPrefix(lines[2], "GET     /users                  user.list") {
+		t.Errorf("%s: unexpected route table\n%s", t.Name(), table.String())
+	}
+}
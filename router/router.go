@@ -1,9 +1,12 @@
 package router
 
 import (
-	"log"
 	"net/http"
+	pathpkg "path"
 	"strings"
+	"sync"
+
+	"github.com/flannel-dev-lab/cyclops/v2/middleware"
 )
 
 var (
@@ -18,17 +21,39 @@ var (
 
 // Router is a struct which handles dispatching requests to different handlers
 type Router struct {
-	tree          *node
-	staticHandler http.Handler
-	staticPath    string
+	tree *node
+	// statics contains the static mounts, ordered so the longest prefix is matched first
+	statics []*static
+	// paramsPool holds the Params used while matching a request, so a lookup does not allocate them. maxParams is
+	// the number of params of the route with the most params and the capacity they are created with
+	paramsPool sync.Pool
+	maxParams  int
+	// routes contains every registered route and named the routes that were given a name
+	routes []*Route
+	named  map[string]*Route
+	// middlewares contains the middleware registered with Use, handler is the dispatch wrapped with them
+	middlewares []middleware.Middlewares
+	handler     http.HandlerFunc
 	// StripTrailingSlashOnRequest removes a trailing slash when registering routes
 	StripTrailingSlashOnRegisteringHandlers bool
 	// NotFoundHandler allows you to pass in a custom NotFoundHandler when a handler
 	// is not found
 	NotFoundHandler http.HandlerFunc
 	// MethodNotAllowedHandler allows you to pass in a custom MethodNotAllowedHandler
-	// when a method is not allowed
+	// when a method is not allowed, the Allow header is set before it is called
 	MethodNotAllowedHandler http.HandlerFunc
+	// HandleOPTIONS answers OPTIONS requests for paths without an OPTIONS handler with the Allow header and a
+	// 204 status code. New enables it
+	HandleOPTIONS bool
+	// RedirectTrailingSlash redirects a request for a path without a handler to the same path with the trailing
+	// slash added or removed, when that path has a handler
+	RedirectTrailingSlash bool
+	// RedirectFixedPath redirects a request for a path without a handler to the cleaned path, with superfluous
+	// elements like ../ or // removed, when the cleaned path has a handler
+	RedirectFixedPath bool
+	// RedirectCaseInsensitive makes RedirectFixedPath look up the cleaned path case-insensitively and redirect to
+	// the casing the route was registered with
+	RedirectCaseInsensitive bool
 }
 
 func New(
@@ -36,16 +61,16 @@ func New(
 	notFoundHandler,
 	methodNotAllowedHandler http.HandlerFunc) *Router {
 
-	node := &node{
-		children:     []*node{},
-		component:    "/",
-		isNamedParam: false,
-		methods:      make(map[string]http.HandlerFunc),
-	}
-
 	router := &Router{
-		tree:                                    node,
+		tree:                                    newNode(""),
+		named:                                   make(map[string]*Route),
 		StripTrailingSlashOnRegisteringHandlers: stripTrailingSlashOnRegisteringHandlers,
+		HandleOPTIONS:                           true,
+	}
+
+	router.paramsPool.New = func() interface{} {
+		params := make(Params, 0, router.maxParams)
+		return &params
 	}
 
 	if notFoundHandler == nil {
@@ -64,51 +89,57 @@ func New(
 }
 
 // Get - Helper method to add HTTP GET Method to router
-func (r *Router) Get(path string, handler http.HandlerFunc) {
-	r.add(http.MethodGet, path, handler)
+func (r *Router) Get(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodGet, path, handler)
 }
 
 // Post - Helper method to add HTTP POST Method to router
-func (r *Router) Post(path string, handler http.HandlerFunc) {
-	r.add(http.MethodPost, path, handler)
+func (r *Router) Post(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodPost, path, handler)
 }
 
 // Connect - Helper method to add HTTP CONNECT Method to router
-func (r *Router) Connect(path string, handler http.HandlerFunc) {
-	r.add(http.MethodConnect, path, handler)
+func (r *Router) Connect(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodConnect, path, handler)
 }
 
 // Delete - Helper method to add HTTP DELETE Method to router
-func (r *Router) Delete(path string, handler http.HandlerFunc) {
-	r.add(http.MethodDelete, path, handler)
+func (r *Router) Delete(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodDelete, path, handler)
 }
 
 // Patch - Helper method to add HTTP PATCH Method to router
-func (r *Router) Patch(path string, handler http.HandlerFunc) {
-	r.add(http.MethodPatch, path, handler)
+func (r *Router) Patch(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodPatch, path, handler)
 }
 
 // Put - Helper method to add HTTP PUT Method to router
-func (r *Router) Put(path string, handler http.HandlerFunc) {
-	r.add(http.MethodPut, path, handler)
+func (r *Router) Put(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodPut, path, handler)
 }
 
 // Trace - Helper method to add HTTP TRACE Method to router
-func (r *Router) Trace(path string, handler http.HandlerFunc) {
-	r.add(http.MethodTrace, path, handler)
+func (r *Router) Trace(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodTrace, path, handler)
 }
 
 // Head - Helper method to add HTTP HEAD Method to router
-func (r *Router) Head(path string, handler http.HandlerFunc) {
-	r.add(http.MethodHead, path, handler)
+func (r *Router) Head(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodHead, path, handler)
 }
 
 // Options - Helper method to add HTTP OPTIONS Method to router
-func (r *Router) Options(path string, handler http.HandlerFunc) {
-	r.add(http.MethodOptions, path, handler)
+func (r *Router) Options(path string, handler http.HandlerFunc) *Route {
+	return r.add(http.MethodOptions, path, handler)
 }
 
-func (r *Router) add(method, path string, handler http.HandlerFunc) {
+func (r *Router) add(method, path string, handler http.HandlerFunc) *Route {
+	return r.register(method, path, handler, false)
+}
+
+// register adds the route to the tree and the list of routes, unnamedCatchAll allows the path to end with a bare
+// catch-all which is only used by Mount
+func (r *Router) register(method, path string, handler http.HandlerFunc, unnamedCatchAll bool) *Route {
 	if method == "" {
 		panic("method must not be empty")
 	}
@@ -125,81 +156,228 @@ func (r *Router) add(method, path string, handler http.HandlerFunc) {
 		path = strings.TrimSuffix(path, "/")
 	}
 
-	r.tree.addNode(method, path, handler)
+	r.tree.addNode(method, path, handler, unnamedCatchAll)
+
+	if count := strings.Count(path, "/:") + strings.Count(path, "/*"); count > r.maxParams {
+		r.maxParams = count
+	}
+
+	route := &Route{router: r, Method: method, Pattern: path}
+
+	// Registering a handler again replaces the existing route, keeping its name
+	for idx, existing := range r.routes {
+		if existing.Method == method && existing.Pattern == path {
+			if existing.name != "" {
+				route.name = existing.name
+				r.named[existing.name] = route
+			}
+			r.routes[idx] = route
+			return route
+		}
+	}
+
+	r.routes = append(r.routes, route)
+
+	return route
 }
 
+// find returns the handler registered for the method and path of the request, the params captured while matching
+// the path are stored in the context of the request the handler is called with. A static mount only serves the
+// request when no route matches its path
 func (r *Router) find(req *http.Request) (http.HandlerFunc, error) {
-	_ = req.ParseForm()
+	// Params captured before the router, like those of a host pattern, are kept in front of the path params
+	existing := ParamsFromContext(req.Context())
+	params := r.getParams()
+	*params = append(*params, existing...)
+
+	node := r.tree.search(req.URL.Path, params)
+	if node == nil {
+		r.putParams(params)
+		if static := r.matchStatic(req.URL.Path); static != nil {
+			return static.ServeHTTP, nil
+		}
+		if fixedPath, ok := r.fixPath(req.URL.Path); ok && req.Method != http.MethodConnect {
+			return redirectHandler(fixedPath), nil
+		}
+		return r.NotFoundHandler, nil
+	}
+
+	handler := node.methods[req.Method]
+	if handler == nil {
+		handler = node.methods[anyMethod]
+	}
+
+	if handler == nil && req.Method == http.MethodHead {
+		handler = headHandler(node.methods[http.MethodGet])
+	}
+
+	if handler == nil {
+		r.putParams(params)
+		allow := node.allowed(r.HandleOPTIONS)
+
+		if req.Method == http.MethodOptions && r.HandleOPTIONS {
+			return func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Allow", allow)
+				w.WriteHeader(http.StatusNoContent)
+			}, nil
+		}
+
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Allow", allow)
+			r.MethodNotAllowedHandler(w, req)
+		}, nil
+	}
+
+	if len(*params) == len(existing) {
+		r.putParams(params)
+		return handler, nil
+	}
+
+	// The params go back to the pool once the handler returns, so they are only valid while it runs
+	return func(w http.ResponseWriter, req *http.Request) {
+		handler(w, req.WithContext(withParams(req.Context(), *params)))
+		r.putParams(params)
+	}, nil
+}
+
+// getParams returns empty Params from the pool
+func (r *Router) getParams() *Params {
+	params := r.paramsPool.Get().(*Params)
+	*params = (*params)[:0]
+	return params
+}
 
-	params := req.Form
+// putParams returns params to the pool
+func (r *Router) putParams(params *Params) {
+	r.paramsPool.Put(params)
+}
 
-	node, _ := r.tree.traverse(strings.Split(req.URL.Path, "/")[1:], params)
-	if handler := node.methods[req.Method]; handler != nil {
-		q := req.URL.Query()
+// fixPath returns the canonical path a request for path should be redirected to, as configured by
+// RedirectTrailingSlash, RedirectFixedPath and RedirectCaseInsensitive
+func (r *Router) fixPath(path string) (string, bool) {
+	if r.RedirectTrailingSlash {
+		if fixedPath := toggleTrailingSlash(path); r.exists(fixedPath) {
+			return fixedPath, true
+		}
+	}
 
-		for key, values := range params {
-			temp := values
-			q.Del(key)
+	if !r.RedirectFixedPath {
+		return "", false
+	}
 
-			for _, value := range temp {
-				q.Add(key, value)
+	candidates := []string{cleanPath(path)}
+	if r.RedirectTrailingSlash {
+		candidates = append(candidates, toggleTrailingSlash(candidates[0]))
+	}
+
+	for _, candidate := range candidates {
+		if r.RedirectCaseInsensitive {
+			if fixed := r.tree.searchCaseInsensitive(candidate, make([]byte, 0, len(candidate))); fixed != nil {
+				candidate = string(fixed)
 			}
 		}
 
-		req.URL.RawQuery = q.Encode()
+		if candidate != path && r.exists(candidate) {
+			return candidate, true
+		}
+	}
 
-		return handler, nil
-	} else {
-		if len(node.methods) == 0 {
-			return r.NotFoundHandler, nil
-		} else {
-			return r.MethodNotAllowedHandler, nil
+	return "", false
+}
+
+// exists tells if a handler is registered for path
+func (r *Router) exists(path string) bool {
+	params := r.getParams()
+	defer r.putParams(params)
+
+	return r.tree.search(path, params) != nil
+}
+
+// redirectHandler redirects to path keeping the query string, GET and HEAD requests are redirected with a 301 and
+// other methods with a 308 so clients repeat the request with the same method and body
+func redirectHandler(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		code := http.StatusPermanentRedirect
+		if req.Method == http.MethodGet || req.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+
+		url := path
+		if req.URL.RawQuery != "" {
+			url += "?" + req.URL.RawQuery
 		}
+
+		http.Redirect(w, req, url, code)
 	}
 }
 
-func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	if strings.Contains(req.URL.Path, r.staticPath) && r.staticPath != "" {
-		r.staticHandler.ServeHTTP(w, req)
-	} else {
-		handler, _ := r.find(req)
-		handler(w, req)
+// toggleTrailingSlash adds a trailing slash to path, or removes it if there is one
+func toggleTrailingSlash(path string) string {
+	if path == "/" {
+		return path
 	}
+
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+
+	return path + "/"
 }
 
-// FileSystem custom file system handler
-type FileSystem struct {
-	fs http.FileSystem
+// cleanPath returns the shortest path equivalent to p, keeping a trailing slash
+func cleanPath(p string) string {
+	cleaned := pathpkg.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
 }
 
-// Open opens file
-func (fs FileSystem) Open(path string) (http.File, error) {
-	f, err := fs.fs.Open(path)
-	if err != nil {
-		log.Println(err)
-		return nil, err
+// headHandler serves a HEAD request with the GET handler, discarding the body it writes. It returns nil when there
+// is no GET handler
+func headHandler(get http.HandlerFunc) http.HandlerFunc {
+	if get == nil {
+		return nil
 	}
 
-	s, err := f.Stat()
-	if err != nil {
-		log.Println(err)
-		return nil, err
+	return func(w http.ResponseWriter, req *http.Request) {
+		get(headResponseWriter{w}, req)
 	}
+}
 
-	if s.IsDir() {
-		index := strings.TrimSuffix(path, "/") + "/index.html"
-		if _, err := fs.fs.Open(index); err != nil {
-			log.Println(err)
-			return nil, err
-		}
+// headResponseWriter is a http.ResponseWriter that writes the headers but discards the body
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write discards the data while reporting it as written
+func (w headResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+// Use adds middleware that wraps the whole dispatch of the router, so it runs for every request including static
+// files and requests answered by the NotFoundHandler or MethodNotAllowedHandler. Middleware's are chained in the
+// order they are specified, the same way as middleware.Chain
+func (r *Router) Use(mws ...middleware.Middlewares) {
+	r.middlewares = append(r.middlewares, mws...)
+
+	r.handler = r.dispatch
+	for _, mw := range r.middlewares {
+		r.handler = mw(r.handler)
 	}
+}
 
-	return f, nil
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.handler != nil {
+		r.handler(w, req)
+	} else {
+		r.dispatch(w, req)
+	}
 }
 
-// RegisterStatic registers a static directory to serve on servePath
-func (r *Router) RegisterStatic(directoryPath, servePath string) {
-	fs := http.FileServer(FileSystem{http.Dir(directoryPath)})
-	r.staticHandler = http.StripPrefix(servePath, fs)
-	r.staticPath = servePath
+// dispatch hands the request to the handler registered for the path or to the static handler
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	handler, _ := r.find(req)
+	handler(w, req)
 }
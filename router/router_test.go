@@ -2,12 +2,10 @@ package router
 
 import (
 	"fmt"
-	"github.com/flannel-dev-lab/cyclops/v2"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
-	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -15,8 +13,12 @@ import (
 )
 
 func TestRouterParam(t *testing.T) {
+	var params Params
+
 	r := New(true, nil, nil)
-	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		params = append(Params(nil), ParamsFromContext(r.Context())...)
+	})
 	req, _ := http.NewRequest("GET", "/users/1", nil)
 	w := httptest.NewRecorder()
 
@@ -27,8 +29,8 @@ func TestRouterParam(t *testing.T) {
 
 	h(w, req)
 
-	if cyclops.Param(req, "id") != "1" {
-		t.Errorf(fmt.Sprintf("%s: params do not match", t.Name()))
+	if params.Get("id") != "1" {
+		t.Errorf("%s: params do not match", t.Name())
 	}
 }
 
@@ -116,6 +118,105 @@ func TestRouterInitializationWithCyclopsMethodNotAllowedHandler(t *testing.T) {
 	}
 }
 
+func TestRouter_AutomaticMethods(t *testing.T) {
+	r := New(true, nil, nil)
+	r.Get("/use", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Handler", "get")
+		fmt.Fprintf(w, "hello")
+	})
+	r.Post("/use", func(w http.ResponseWriter, r *http.Request) {})
+	r.Put("/put", func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		Method        string
+		Path          string
+		HandleOPTIONS bool
+		Code          int
+		Allow         string
+		Body          string
+	}{
+		{http.MethodDelete, "/use", true, http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, POST", ""},
+		{http.MethodDelete, "/use", false, http.StatusMethodNotAllowed, "GET, HEAD, POST", ""},
+		{http.MethodOptions, "/use", true, http.StatusNoContent, "GET, HEAD, OPTIONS, POST", ""},
+		{http.MethodOptions, "/use", false, http.StatusMethodNotAllowed, "GET, HEAD, POST", ""},
+		{http.MethodHead, "/use", true, http.StatusOK, "", ""},
+		{http.MethodHead, "/put", true, http.StatusMethodNotAllowed, "OPTIONS, PUT", ""},
+		{http.MethodGet, "/use", true, http.StatusOK, "", "hello"},
+	}
+
+	for _, testCase := range cases {
+		r.HandleOPTIONS = testCase.HandleOPTIONS
+
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s %s", t.Name(), testCase.Code, w.Code, testCase.Method, testCase.Path)
+		}
+
+		if w.Header().Get("Allow") != testCase.Allow {
+			t.Errorf("%s: expected Allow '%s' got '%s' for %s %s", t.Name(), testCase.Allow, w.Header().Get("Allow"), testCase.Method, testCase.Path)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected body '%s' got '%s' for %s %s", t.Name(), testCase.Body, w.Body.String(), testCase.Method, testCase.Path)
+		}
+
+		if testCase.Method == http.MethodHead && testCase.Code == http.StatusOK && w.Header().Get("X-Handler") != "get" {
+			t.Errorf("%s: HEAD was not served by the GET handler", t.Name())
+		}
+	}
+}
+
+func TestRouter_Redirects(t *testing.T) {
+	r := New(false, nil, nil)
+	r.Get("/hello", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/Users/:id/", func(w http.ResponseWriter, r *http.Request) {})
+
+	cases := []struct {
+		Method          string
+		Path            string
+		TrailingSlash   bool
+		FixedPath       bool
+		CaseInsensitive bool
+		Code            int
+		Location        string
+	}{
+		{http.MethodGet, "/hello/", false, false, false, http.StatusNotFound, ""},
+		{http.MethodGet, "/hello/", true, false, false, http.StatusMovedPermanently, "/hello"},
+		{http.MethodGet, "/hello/?a=1", true, false, false, http.StatusMovedPermanently, "/hello?a=1"},
+		{http.MethodPost, "/Users/1", true, false, false, http.StatusPermanentRedirect, "/Users/1/"},
+		{http.MethodGet, "/a//../hello", false, false, false, http.StatusNotFound, ""},
+		{http.MethodGet, "/a//../hello", false, true, false, http.StatusMovedPermanently, "/hello"},
+		{http.MethodGet, "/a//../hello/", false, true, false, http.StatusNotFound, ""},
+		{http.MethodGet, "/a//../hello/", true, true, false, http.StatusMovedPermanently, "/hello"},
+		{http.MethodGet, "/HELLO", false, true, false, http.StatusNotFound, ""},
+		{http.MethodGet, "/HELLO", false, true, true, http.StatusMovedPermanently, "/hello"},
+		{http.MethodPost, "/users/../users/Me/", false, true, true, http.StatusPermanentRedirect, "/Users/Me/"},
+	}
+
+	for _, testCase := range cases {
+		r.RedirectTrailingSlash = testCase.TrailingSlash
+		r.RedirectFixedPath = testCase.FixedPath
+		r.RedirectCaseInsensitive = testCase.CaseInsensitive
+
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s %s", t.Name(), testCase.Code, w.Code, testCase.Method, testCase.Path)
+		}
+
+		if w.Header().Get("Location") != testCase.Location {
+			t.Errorf("%s: expected Location '%s' got '%s' for %s %s", t.Name(), testCase.Location, w.Header().Get("Location"), testCase.Method, testCase.Path)
+		}
+	}
+}
+
 func TestRouter_IncorrectInputs(t *testing.T) {
 	cases := []struct {
 		Method  string
@@ -154,6 +255,44 @@ func TestRouter_ServeHTTP(t *testing.T) {
 	}
 }
 
+func TestRouter_Use(t *testing.T) {
+	r := New(true, nil, nil)
+	r.Post("/use", func(w http.ResponseWriter, r *http.Request) {})
+
+	r.Use(func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			h(w, r)
+		}
+	})
+
+	cases := []struct {
+		Method string
+		Path   string
+		Code   int
+	}{
+		{http.MethodPost, "/use", http.StatusOK},
+		{http.MethodGet, "/use", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/missing", http.StatusNotFound},
+		{http.MethodOptions, "/missing", http.StatusNotFound},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s %s", t.Name(), testCase.Code, w.Code, testCase.Method, testCase.Path)
+		}
+
+		if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("%s: middleware was not applied to %s %s", t.Name(), testCase.Method, testCase.Path)
+		}
+	}
+}
+
 func TestRouter_FileServer(t *testing.T) {
 	err := os.Mkdir("static", 0777)
 	if err != nil {
@@ -213,7 +352,7 @@ func TestRouter_FileServerOpen(t *testing.T) {
 	}
 
 	for _, testCase := range cases {
-		fs := FileSystem{http.Dir(testCase.DirectoryPath)}
+		fs := Dir(testCase.DirectoryPath)
 
 		_, err := fs.Open(testCase.DirectoryPath)
 		fmt.Println(err)
@@ -226,8 +365,12 @@ func TestRouter_FileServerOpen(t *testing.T) {
 }
 
 func TestRouter_TwoParam(t *testing.T) {
+	var params Params
+
 	r := New(false, nil, nil)
-	r.Get("/users/:uid/files/:fid", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/users/:uid/files/:fid", func(w http.ResponseWriter, r *http.Request) {
+		params = append(Params(nil), ParamsFromContext(r.Context())...)
+	})
 
 	req, _ := http.NewRequest("GET", "/users/1/files/1", nil)
 	w := httptest.NewRecorder()
@@ -239,18 +382,22 @@ func TestRouter_TwoParam(t *testing.T) {
 
 	h(w, req)
 
-	if cyclops.Param(req, "uid") != "1" {
-		t.Errorf(fmt.Sprintf("%s: params do not match", t.Name()))
+	if params.Get("uid") != "1" {
+		t.Errorf("%s: params do not match", t.Name())
 	}
 
-	if cyclops.Param(req, "fid") != "1" {
-		t.Errorf(fmt.Sprintf("%s: params do not match", t.Name()))
+	if params.Get("fid") != "1" {
+		t.Errorf("%s: params do not match", t.Name())
 	}
 }
 
 func TestRouterMicroParam(t *testing.T) {
+	var params Params
+
 	r := New(false, nil, nil)
-	r.Get("/:a/:b/:c", func(w http.ResponseWriter, r *http.Request) {})
+	r.Get("/:a/:b/:c", func(w http.ResponseWriter, r *http.Request) {
+		params = append(Params(nil), ParamsFromContext(r.Context())...)
+	})
 
 	req, _ := http.NewRequest("GET", "/1/2/3", nil)
 	w := httptest.NewRecorder()
@@ -262,16 +409,288 @@ func TestRouterMicroParam(t *testing.T) {
 
 	h(w, req)
 
-	if cyclops.Param(req, "a") != "1" {
-		t.Errorf(fmt.Sprintf("%s: params do not match", t.Name()))
+	if params.Get("a") != "1" {
+		t.Errorf("%s: params do not match", t.Name())
 	}
 
-	if cyclops.Param(req, "b") != "2" {
-		t.Errorf(fmt.Sprintf("%s: params do not match", t.Name()))
+	if params.Get("b") != "2" {
+		t.Errorf("%s: params do not match", t.Name())
 	}
 
-	if cyclops.Param(req, "c") != "3" {
-		t.Errorf(fmt.Sprintf("%s: params do not match", t.Name()))
+	if params.Get("c") != "3" {
+		t.Errorf("%s: params do not match", t.Name())
+	}
+}
+
+// capture returns a handler that writes body and stores the params of the request in params
+func capture(body string, params *Params) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The params go back to the pool once the handler returns, so they are copied to be checked afterwards
+		*params = append(Params(nil), ParamsFromContext(r.Context())...)
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestRouter_ParamsDoNotTouchRequest(t *testing.T) {
+	var params Params
+	var body []byte
+
+	r := New(false, nil, nil)
+	r.Post("/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		params = append(Params(nil), ParamsFromContext(r.Context())...)
+		body, _ = ioutil.ReadAll(r.Body)
+	})
+
+	req, _ := http.NewRequest("POST", "/users/1?id=2", strings.NewReader("name=cyclops"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if params.Get("id") != "1" {
+		t.Errorf("%s: expected path param '1' got '%s'", t.Name(), params.Get("id"))
+	}
+
+	if req.URL.RawQuery != "id=2" {
+		t.Errorf("%s: expected query 'id=2' got '%s'", t.Name(), req.URL.RawQuery)
+	}
+
+	if string(body) != "name=cyclops" {
+		t.Errorf("%s: expected body 'name=cyclops' got '%s'", t.Name(), string(body))
+	}
+}
+
+func TestRouter_CatchAll(t *testing.T) {
+	var params Params
+
+	r := New(false, nil, nil)
+	r.Get("/files/*path", capture("catch-all", &params))
+	r.Get("/files/readme", capture("literal", &params))
+	r.Get("/files/:name/raw", capture("named", &params))
+
+	cases := []struct {
+		Path string
+		Body string
+		Key  string
+		Val  string
+	}{
+		{"/files/readme", "literal", "", ""},
+		{"/files/a.css/raw", "named", "name", "a.css"},
+		{"/files/a.css", "catch-all", "path", "a.css"},
+		{"/files/css/site/a.css", "catch-all", "path", "css/site/a.css"},
+		{"/files/css/", "catch-all", "path", "css/"},
+	}
+
+	for _, testCase := range cases {
+		params = nil
+		req, _ := http.NewRequest("GET", testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		h, err := r.find(req)
+		if err != nil {
+			t.Errorf("%s: unable to get handler: %s", t.Name(), err.Error())
+		}
+
+		h(w, req)
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Body, w.Body.String(), testCase.Path)
+		}
+
+		if testCase.Key != "" && params.Get(testCase.Key) != testCase.Val {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Val, params.Get(testCase.Key), testCase.Path)
+		}
+	}
+}
+
+func TestRouter_CatchAllNotLast(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: should have panicked", t.Name())
+		}
+	}()
+
+	r := New(false, nil, nil)
+	r.Get("/files/*path/edit", func(w http.ResponseWriter, r *http.Request) {})
+}
+
+func TestRouter_CatchAllUnnamed(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: should have panicked", t.Name())
+		}
+	}()
+
+	r := New(false, nil, nil)
+	r.Get("/files/*", func(w http.ResponseWriter, r *http.Request) {})
+}
+
+func TestRouter_MatchingPriority(t *testing.T) {
+	var params Params
+
+	r := New(false, nil, nil)
+	r.Get("/users/:id", capture("named", &params))
+	r.Get("/users/me", capture("literal", &params))
+	r.Get("/users/me/:tab/edit", capture("literal-edit", &params))
+	r.Get("/users/:id/settings", capture("named-settings", &params))
+
+	cases := []struct {
+		Path string
+		Body string
+		ID   string
+	}{
+		{"/users/me", "literal", ""},
+		{"/users/1", "named", "1"},
+		{"/users/me/profile/edit", "literal-edit", ""},
+		{"/users/me/settings", "named-settings", "me"},
+	}
+
+	for _, testCase := range cases {
+		params = nil
+		req, _ := http.NewRequest("GET", testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		h, err := r.find(req)
+		if err != nil {
+			t.Errorf("%s: unable to get handler: %s", t.Name(), err.Error())
+		}
+
+		h(w, req)
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Body, w.Body.String(), testCase.Path)
+		}
+
+		if params.Get("id") != testCase.ID {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.ID, params.Get("id"), testCase.Path)
+		}
+
+		if params.Get("tab") != "" && testCase.Body != "literal-edit" {
+			t.Errorf("%s: abandoned branch left param 'tab' behind for %s", t.Name(), testCase.Path)
+		}
+	}
+}
+
+func TestRouter_ConstrainedParams(t *testing.T) {
+	var params Params
+
+	r := New(false, nil, nil)
+	r.Get("/users/:id<int>", capture("int", &params))
+	r.Get("/users/:name", capture("name", &params))
+	r.Get("/posts/:id<int>", capture("post", &params))
+	r.Get("/posts/:slug<[a-z0-9-]+>", capture("slug", &params))
+	r.Get("/keys/:key<uuid>", capture("uuid", &params))
+
+	cases := []struct {
+		Path string
+		Code int
+		Body string
+		Key  string
+		Val  string
+	}{
+		{"/users/42", http.StatusOK, "int", "id", "42"},
+		{"/users/abc", http.StatusOK, "name", "name", "abc"},
+		{"/posts/12", http.StatusOK, "post", "id", "12"},
+		{"/posts/hello-world-2", http.StatusOK, "slug", "slug", "hello-world-2"},
+		{"/posts/Hello_World", http.StatusNotFound, "", "", ""},
+		{"/keys/6ba7b810-9dad-11d1-80b4-00c04fd430c8", http.StatusOK, "uuid", "key", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"/keys/6ba7b810", http.StatusNotFound, "", "", ""},
+	}
+
+	for _, testCase := range cases {
+		params = nil
+		req, _ := http.NewRequest("GET", testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s", t.Name(), testCase.Code, w.Code, testCase.Path)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Body, w.Body.String(), testCase.Path)
+		}
+
+		if params.Get(testCase.Key) != testCase.Val {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Val, params.Get(testCase.Key), testCase.Path)
+		}
+	}
+}
+
+func TestRouter_SharedPrefixes(t *testing.T) {
+	var params Params
+
+	r := New(false, nil, nil)
+	r.Get("/users/mega", capture("mega", &params))
+	r.Get("/users/me", capture("me", &params))
+	r.Get("/usa", capture("usa", &params))
+	r.Get("/users/:id", capture("named", &params))
+	r.Get("/users/", capture("users", &params))
+
+	cases := []struct {
+		Path string
+		Code int
+		Body string
+		ID   string
+	}{
+		{"/users/me", http.StatusOK, "me", ""},
+		{"/users/mega", http.StatusOK, "mega", ""},
+		{"/users/meg", http.StatusOK, "named", "meg"},
+		{"/users/megabyte", http.StatusOK, "named", "megabyte"},
+		{"/usa", http.StatusOK, "usa", ""},
+		{"/users/", http.StatusOK, "users", ""},
+		{"/us", http.StatusNotFound, "", ""},
+		{"/users/me/", http.StatusNotFound, "", ""},
+	}
+
+	for _, testCase := range cases {
+		params = nil
+		req, _ := http.NewRequest("GET", testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: expected %d got %d for %s", t.Name(), testCase.Code, w.Code, testCase.Path)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.Body, w.Body.String(), testCase.Path)
+		}
+
+		if params.Get("id") != testCase.ID {
+			t.Errorf("%s: expected '%s' got '%s' for %s", t.Name(), testCase.ID, params.Get("id"), testCase.Path)
+		}
+	}
+}
+
+func TestRouter_ConflictingRoutes(t *testing.T) {
+	cases := []struct {
+		Existing string
+		Path     string
+	}{
+		{"/users/:id", "/users/:uid"},
+		{"/users/:id/files", "/users/:uid/posts"},
+		{"/files/*path", "/files/*filepath"},
+		{"/users/:id<int>", "/users/:uid<int>"},
+		{"/users/:id<[0-9]+>", "/users/:uid<[0-9]+>"},
+		{"/users", "/users/:id<[0-9+>"},
+		{"/files", "/files/*path<[a-z]+>"},
+	}
+
+	for _, testCase := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: registering %s after %s should have panicked", t.Name(), testCase.Path, testCase.Existing)
+				}
+			}()
+
+			r := New(false, nil, nil)
+			r.Get(testCase.Existing, func(w http.ResponseWriter, r *http.Request) {})
+			r.Get(testCase.Path, func(w http.ResponseWriter, r *http.Request) {})
+		}()
 	}
 }
 
@@ -687,7 +1106,7 @@ func Benchmark_GetRoute(b *testing.B) {
 		}
 	}
 
-	params := url.Values{}
+	params := make(Params, 0, 8)
 
 	rand.Seed(time.Now().UnixNano())
 	min := 0
@@ -698,6 +1117,67 @@ func Benchmark_GetRoute(b *testing.B) {
 
 		randomIdx := rand.Intn(max-min+1) + min
 
-		r.tree.traverse(strings.Split(api[randomIdx].path, "/"), params)
+		params = params[:0]
+		r.tree.search(api[randomIdx].path, &params)
+	}
+}
+
+// discardResponseWriter is a http.ResponseWriter that does not record anything, so benchmarks of ServeHTTP only
+// measure the router
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header {
+	return nil
+}
+
+func (discardResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (discardResponseWriter) WriteHeader(int) {}
+
+func Benchmark_ServeHTTPStatic(b *testing.B) {
+	r := New(false, nil, nil)
+	for _, testCase := range api {
+		r.add(testCase.method, testCase.path, testCase.handler)
+	}
+
+	req, _ := http.NewRequest("GET", "/user/keys", nil)
+	w := discardResponseWriter{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func Benchmark_ServeHTTPParam(b *testing.B) {
+	r := New(false, nil, nil)
+	for _, testCase := range api {
+		r.add(testCase.method, testCase.path, testCase.handler)
+	}
+
+	req, _ := http.NewRequest("GET", "/users/cyclops/following/gopher", nil)
+	w := discardResponseWriter{}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func TestRouter_StaticLookupDoesNotAllocate(t *testing.T) {
+	r := New(false, nil, nil)
+	for _, testCase := range api {
+		r.add(testCase.method, testCase.path, testCase.handler)
+	}
+
+	req, _ := http.NewRequest("GET", "/user/keys", nil)
+	w := discardResponseWriter{}
+
+	if allocs := testing.AllocsPerRun(100, func() { r.ServeHTTP(w, req) }); allocs != 0 {
+		t.Errorf("%s: expected 0 allocations got %v", t.Name(), allocs)
 	}
 }
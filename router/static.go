@@ -0,0 +1,393 @@
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSystem custom file system handler
+type FileSystem struct {
+	fs http.FileSystem
+	// DirectoryListing lists the contents of a directory without an index file, when it is disabled such a
+	// directory is not found
+	DirectoryListing bool
+	// IndexFile is the file served for a directory, index.html when empty
+	IndexFile string
+	// Fallback is the file served instead of a not found response for requests of a page, like /app/users/1 of a
+	// single page application. Requests accepting text/html or without a file extension are considered to request a
+	// page, so a missing /app/main.js is still not found. Routes registered below the path of the FileSystem are not
+	// affected, the fallback is only served for paths no route matches. There is no fallback when it is empty
+	Fallback string
+	// Precompressed serves the .br or .gz sibling of a file, like app.js.br for app.js, when the client accepts its
+	// encoding
+	Precompressed bool
+	// Fingerprinted tells if a file name contains a hash of its content, so the file can be cached forever. When it
+	// is nil names like app.3f2a9c1b.js or app-3f2a9c1b.js, with a hexadecimal hash of at least 8 digits including a
+	// letter, are considered fingerprinted. Names with a run of decimal digits like invoice-12345678.pdf are not, as
+	// they are usually dates or numbers of files which can change
+	Fingerprinted func(name string) bool
+}
+
+// fingerprint matches the file names with a hexadecimal content hash before the extension
+var fingerprint = regexp.MustCompile(`[.-]([0-9a-fA-F]{8,})\.[^./]+$`)
+
+// isFingerprinted tells if the file name contains a hexadecimal content hash, the hash has to contain a letter so
+// decimal numbers like dates are not mistaken for one
+func isFingerprinted(name string) bool {
+	match := fingerprint.FindStringSubmatch(name)
+
+	return match != nil && strings.ContainsAny(match[1], "abcdefABCDEF")
+}
+
+// encoding is the content encoding of a precompressed sibling of a file and the extension of its name
+type encoding struct {
+	name      string
+	extension string
+}
+
+// encodings contains the content encodings of the precompressed siblings of a file, in the order of preference
+var encodings = []encoding{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// etagKey identifies a version of a file in the ETag cache of a static mount
+type etagKey struct {
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+// Dir returns a FileSystem serving the files of the directory at directoryPath
+func Dir(directoryPath string) FileSystem {
+	return FileSystem{fs: http.Dir(directoryPath)}
+}
+
+// FS returns a FileSystem serving the files of fsys, like an embed.FS
+func FS(fsys fs.FS) FileSystem {
+	return FileSystem{fs: http.FS(fsys)}
+}
+
+// Open opens file
+func (fs FileSystem) Open(path string) (http.File, error) {
+	f, err := fs.fs.Open(path)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	s, err := f.Stat()
+	if err != nil {
+		log.Println(err)
+		_ = f.Close()
+		return nil, err
+	}
+
+	if s.IsDir() && !fs.DirectoryListing {
+		index := strings.TrimSuffix(path, "/") + "/" + fs.index()
+		i, err := fs.fs.Open(index)
+		if err != nil {
+			log.Println(err)
+			_ = f.Close()
+			return nil, err
+		}
+		_ = i.Close()
+	}
+
+	return f, nil
+}
+
+// index returns the name of the file served for a directory
+func (fs FileSystem) index() string {
+	if fs.IndexFile == "" {
+		return "index.html"
+	}
+
+	return fs.IndexFile
+}
+
+// static serves the files of a FileSystem under a path prefix
+type static struct {
+	prefix     string
+	fileSystem FileSystem
+	// fileServer lists the directories without an index file
+	fileServer http.Handler
+	// etags caches the ETags of the served files by etagKey, so a file is only hashed once per version
+	etags sync.Map
+}
+
+// RegisterStatic registers a static directory to serve on servePath
+func (r *Router) RegisterStatic(directoryPath, servePath string) {
+	r.RegisterFileSystem(Dir(directoryPath), servePath)
+}
+
+// RegisterFileSystem registers a FileSystem to serve on servePath, several file systems can be registered on
+// different paths and the one with the longest matching path serves the request. Routes take priority, a
+// FileSystem only serves the paths no route matches. Registering a FileSystem on a path again replaces the previous
+// one
+func (r *Router) RegisterFileSystem(fileSystem FileSystem, servePath string) {
+	if len(servePath) < 1 || servePath[0] != '/' {
+		panic("path must begin with '/' in path '" + servePath + "'")
+	}
+
+	if fileSystem.fs == nil {
+		panic("file system must not be nil")
+	}
+
+	mount := &static{
+		prefix:     strings.TrimSuffix(servePath, "/"),
+		fileSystem: fileSystem,
+	}
+	mount.fileServer = http.StripPrefix(mount.prefix, http.FileServer(fileSystem))
+
+	for idx, existing := range r.statics {
+		if existing.prefix == mount.prefix {
+			r.statics[idx] = mount
+			return
+		}
+	}
+
+	r.statics = append(r.statics, mount)
+	sort.SliceStable(r.statics, func(i, j int) bool {
+		return len(r.statics[i].prefix) > len(r.statics[j].prefix)
+	})
+}
+
+// matchStatic returns the static mount serving the path, or nil if there is none. A mount on /static serves
+// /static and the paths below it, but not /statics
+func (r *Router) matchStatic(urlPath string) *static {
+	for _, mount := range r.statics {
+		if urlPath == mount.prefix || strings.HasPrefix(urlPath, mount.prefix+"/") {
+			return mount
+		}
+	}
+
+	return nil
+}
+
+func (s *static) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rest := strings.TrimPrefix(req.URL.Path, s.prefix)
+	if rest == "" {
+		http.Redirect(w, req, s.prefix+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	name := path.Clean(rest)
+
+	f, err := s.fileSystem.fs.Open(name)
+	if err != nil {
+		if os.IsNotExist(err) && s.fileSystem.Fallback != "" && requestsPage(req) {
+			s.serveFallback(w, req)
+		} else {
+			serveError(w, err)
+		}
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+
+	if stat.IsDir() {
+		if !strings.HasSuffix(rest, "/") {
+			http.Redirect(w, req, path.Base(req.URL.Path)+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		index, err := s.fileSystem.fs.Open(strings.TrimSuffix(name, "/") + "/" + s.fileSystem.index())
+		if err != nil {
+			if s.fileSystem.DirectoryListing {
+				s.fileServer.ServeHTTP(w, req)
+			} else {
+				serveError(w, err)
+			}
+			return
+		}
+		defer index.Close()
+
+		if stat, err = index.Stat(); err != nil || stat.IsDir() {
+			http.NotFound(w, req)
+			return
+		}
+		f = index
+		name = strings.TrimSuffix(name, "/") + "/" + s.fileSystem.index()
+	}
+
+	s.serveFile(w, req, name, f, stat)
+}
+
+// serveFallback serves the Fallback file of the FileSystem
+func (s *static) serveFallback(w http.ResponseWriter, req *http.Request) {
+	name := path.Clean("/" + s.fileSystem.Fallback)
+
+	f, err := s.fileSystem.fs.Open(name)
+	if err != nil {
+		serveError(w, err)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	s.serveFile(w, req, name, f, stat)
+}
+
+// serveFile serves the file opened from name with a strong ETag and a Cache-Control header, fingerprinted files are
+// cached forever and the others are revalidated with their ETag. Conditional and range requests are answered by
+// http.ServeContent
+func (s *static) serveFile(w http.ResponseWriter, req *http.Request, name string, f http.File, stat fs.FileInfo) {
+	header := w.Header()
+
+	fingerprinted := isFingerprinted
+	if s.fileSystem.Fingerprinted != nil {
+		fingerprinted = s.fileSystem.Fingerprinted
+	}
+
+	if fingerprinted(path.Base(name)) {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "no-cache")
+	}
+
+	if s.fileSystem.Precompressed {
+		header.Add("Vary", "Accept-Encoding")
+
+		if encoded, encodedStat, encoding := s.openPrecompressed(req, name); encoded != nil {
+			defer encoded.Close()
+
+			contentType := mime.TypeByExtension(path.Ext(name))
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+
+			header.Set("Content-Type", contentType)
+			header.Set("Content-Encoding", encoding.name)
+			f, stat, name = encoded, encodedStat, name+encoding.extension
+		}
+	}
+
+	if etag, err := s.etag(name, f, stat); err == nil {
+		header.Set("ETag", etag)
+	} else {
+		log.Println(err)
+	}
+
+	// The name is only used to detect the content type, which is already set for a precompressed file
+	http.ServeContent(w, req, path.Base(name), stat.ModTime(), f)
+}
+
+// openPrecompressed opens the precompressed sibling of the file at name in the most preferred encoding the client
+// accepts, it returns a nil file when there is none
+func (s *static) openPrecompressed(req *http.Request, name string) (http.File, fs.FileInfo, encoding) {
+	acceptEncoding := req.Header.Get("Accept-Encoding")
+
+	for _, candidate := range encodings {
+		if !acceptsEncoding(acceptEncoding, candidate.name) {
+			continue
+		}
+
+		f, err := s.fileSystem.fs.Open(name + candidate.extension)
+		if err != nil {
+			continue
+		}
+
+		stat, err := f.Stat()
+		if err != nil || stat.IsDir() {
+			_ = f.Close()
+			continue
+		}
+
+		return f, stat, candidate
+	}
+
+	return nil, nil, encoding{}
+}
+
+// etag returns the strong ETag of the file, derived from a hash of its content. The file is read from the start
+// and rewound, the ETag is cached for the name, modification time and size of the file
+func (s *static) etag(name string, f http.File, stat fs.FileInfo) (string, error) {
+	key := etagKey{name: name, modTime: stat.ModTime(), size: stat.Size()}
+	if etag, ok := s.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	etag := `"` + base64.RawURLEncoding.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	s.etags.Store(key, etag)
+
+	return etag, nil
+}
+
+// acceptsEncoding tells if the Accept-Encoding header lists the encoding without refusing it with a zero quality
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, quality, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+			continue
+		}
+
+		quality = strings.TrimSpace(quality)
+		if !strings.HasPrefix(quality, "q=") {
+			return true
+		}
+
+		q, err := strconv.ParseFloat(quality[len("q="):], 64)
+		return err == nil && q > 0
+	}
+
+	return false
+}
+
+// requestsPage tells if the request is made for a page rather than an asset, that is when it accepts text/html or
+// its path has no file extension
+func requestsPage(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if strings.Contains(req.Header.Get("Accept"), "text/html") {
+		return true
+	}
+
+	return path.Ext(req.URL.Path) == ""
+}
+
+// serveError answers with the status code matching the error of opening a file
+func serveError(w http.ResponseWriter, err error) {
+	switch {
+	case os.IsNotExist(err):
+		http.Error(w, "404 page not found", http.StatusNotFound)
+	case os.IsPermission(err):
+		http.Error(w, "403 Forbidden", http.StatusForbidden)
+	default:
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,361 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRouter_RegisterFileSystem(t *testing.T) {
+	assets := FS(fstest.MapFS{
+		"app.js":          {Data: []byte("assets app.js")},
+		"docs/index.html": {Data: []byte("assets docs")},
+		"empty/file.txt":  {Data: []byte("assets empty")},
+	})
+
+	public := FS(fstest.MapFS{
+		"index.html": {Data: []byte("public index")},
+		"app.js":     {Data: []byte("public app.js")},
+	})
+
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(public, "/static/")
+	r.RegisterFileSystem(assets, "/static/assets")
+	r.Get("/api/static/:file", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "api %s", ParamsFromContext(r.Context()).Get("file"))
+	})
+
+	cases := []struct {
+		Path string
+		Code int
+		Body string
+	}{
+		{"/static/", http.StatusOK, "public index"},
+		{"/static/app.js", http.StatusOK, "public app.js"},
+		{"/static/assets/app.js", http.StatusOK, "assets app.js"},
+		{"/static/assets/docs/", http.StatusOK, "assets docs"},
+		{"/static/assets/docs", http.StatusMovedPermanently, ""},
+		{"/static/assets/empty/", http.StatusNotFound, ""},
+		{"/static/missing.js", http.StatusNotFound, ""},
+		{"/static/assets/../app.js", http.StatusOK, "assets app.js"},
+		{"/static", http.StatusMovedPermanently, ""},
+		{"/staticfile", http.StatusNotFound, ""},
+		{"/api/static/x", http.StatusOK, "api x"},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(http.MethodGet, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: %s: expected %d got %d", t.Name(), testCase.Path, testCase.Code, w.Code)
+		}
+
+		if testCase.Body != "" && w.Body.String() != testCase.Body {
+			t.Errorf("%s: %s: expected %q got %q", t.Name(), testCase.Path, testCase.Body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_RegisterFileSystemRoot(t *testing.T) {
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(FS(fstest.MapFS{
+		"index.html":    {Data: []byte("root index")},
+		"api/users":     {Data: []byte("root users")},
+		"static/app.js": {Data: []byte("root app.js")},
+	}), "/")
+	r.Get("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "api users")
+	})
+	r.Get("/static/:file", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "route %s", ParamsFromContext(r.Context()).Get("file"))
+	})
+
+	cases := []struct {
+		Method string
+		Path   string
+		Code   int
+		Body   string
+	}{
+		{http.MethodGet, "/", http.StatusOK, "root index"},
+		{http.MethodGet, "/api/users", http.StatusOK, "api users"},
+		{http.MethodGet, "/static/app.js", http.StatusOK, "route app.js"},
+		{http.MethodPost, "/api/users", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/missing.js", http.StatusNotFound, ""},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: %s %s: expected %d got %d", t.Name(), testCase.Method, testCase.Path, testCase.Code, w.Code)
+		}
+
+		if testCase.Body != "" && w.Body.String() != testCase.Body {
+			t.Errorf("%s: %s %s: expected %q got %q", t.Name(), testCase.Method, testCase.Path, testCase.Body, w.Body.String())
+		}
+	}
+}
+
+func TestRouter_RegisterFileSystemReplaces(t *testing.T) {
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(FS(fstest.MapFS{"a.txt": {Data: []byte("first")}}), "/files")
+	r.RegisterFileSystem(FS(fstest.MapFS{"a.txt": {Data: []byte("second")}}), "/files/")
+
+	req, _ := http.NewRequest(http.MethodGet, "/files/a.txt", nil)
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "second" {
+		t.Errorf("%s: expected %q got %q", t.Name(), "second", w.Body.String())
+	}
+}
+
+func TestFileSystem_DirectoryListing(t *testing.T) {
+	files := fstest.MapFS{
+		"docs/readme.txt": {Data: []byte("readme")},
+		"site/home.html":  {Data: []byte("home")},
+	}
+
+	listing := FS(files)
+	listing.DirectoryListing = true
+
+	index := FS(files)
+	index.IndexFile = "home.html"
+
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(listing, "/listing")
+	r.RegisterFileSystem(index, "/index")
+
+	cases := []struct {
+		Path     string
+		Code     int
+		Contains string
+	}{
+		{"/listing/docs/", http.StatusOK, "readme.txt"},
+		{"/index/site/", http.StatusOK, "home"},
+		{"/index/docs/", http.StatusNotFound, ""},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(http.MethodGet, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: %s: expected %d got %d", t.Name(), testCase.Path, testCase.Code, w.Code)
+		}
+
+		if !strings.Contains(w.Body.String(), testCase.Contains) {
+			t.Errorf("%s: %s: expected body to contain %q got %q", t.Name(), testCase.Path, testCase.Contains, w.Body.String())
+		}
+	}
+}
+
+func TestFileSystem_Fallback(t *testing.T) {
+	app := FS(fstest.MapFS{
+		"index.html": {Data: []byte("app index")},
+		"main.js":    {Data: []byte("app main.js")},
+	})
+	app.Fallback = "index.html"
+
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(app, "/app/")
+	r.Get("/api/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "user %s", ParamsFromContext(r.Context()).Get("id"))
+	})
+	r.Get("/app/api", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "app api")
+	})
+
+	cases := []struct {
+		Method string
+		Path   string
+		Accept string
+		Code   int
+		Body   string
+	}{
+		{http.MethodGet, "/app/main.js", "", http.StatusOK, "app main.js"},
+		{http.MethodGet, "/app/users/1", "", http.StatusOK, "app index"},
+		{http.MethodGet, "/app/users/1.json", "text/html,application/xhtml+xml", http.StatusOK, "app index"},
+		{http.MethodGet, "/app/missing.js", "*/*", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodPost, "/app/users/1", "", http.StatusNotFound, "404 page not found\n"},
+		{http.MethodGet, "/api/users/1", "text/html", http.StatusOK, "user 1"},
+		{http.MethodGet, "/app/api", "text/html", http.StatusOK, "app api"},
+		{http.MethodGet, "/api/missing", "text/html", http.StatusNotFound, ""},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(testCase.Method, testCase.Path, nil)
+		if testCase.Accept != "" {
+			req.Header.Set("Accept", testCase.Accept)
+		}
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: %s %s: expected %d got %d", t.Name(), testCase.Method, testCase.Path, testCase.Code, w.Code)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: %s %s: expected %q got %q", t.Name(), testCase.Method, testCase.Path, testCase.Body, w.Body.String())
+		}
+	}
+}
+
+func TestFileSystem_FallbackAtRoot(t *testing.T) {
+	app := FS(fstest.MapFS{
+		"index.html": {Data: []byte("app index")},
+	})
+	app.Fallback = "index.html"
+
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(app, "/")
+	r.Get("/api/users/:id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "user %s", ParamsFromContext(r.Context()).Get("id"))
+	})
+
+	cases := []struct {
+		Path string
+		Code int
+		Body string
+	}{
+		{"/api/users/1", http.StatusOK, "user 1"},
+		{"/users/1", http.StatusOK, "app index"},
+		{"/", http.StatusOK, "app index"},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(http.MethodGet, testCase.Path, nil)
+		req.Header.Set("Accept", "text/html")
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != testCase.Code {
+			t.Errorf("%s: %s: expected %d got %d", t.Name(), testCase.Path, testCase.Code, w.Code)
+		}
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: %s: expected %q got %q", t.Name(), testCase.Path, testCase.Body, w.Body.String())
+		}
+	}
+}
+
+func TestFileSystem_Precompressed(t *testing.T) {
+	assets := FS(fstest.MapFS{
+		"app.js":    {Data: []byte("plain")},
+		"app.js.br": {Data: []byte("brotli")},
+		"app.js.gz": {Data: []byte("gzip")},
+		"style.css": {Data: []byte("style")},
+	})
+	assets.Precompressed = true
+
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(assets, "/assets")
+
+	cases := []struct {
+		Path           string
+		AcceptEncoding string
+		Body           string
+		Encoding       string
+		ContentType    string
+	}{
+		{"/assets/app.js", "", "plain", "", "text/javascript"},
+		{"/assets/app.js", "gzip, deflate, br", "brotli", "br", "text/javascript"},
+		{"/assets/app.js", "gzip, br;q=0", "gzip", "gzip", "text/javascript"},
+		{"/assets/app.js", "deflate", "plain", "", "text/javascript"},
+		{"/assets/style.css", "br", "style", "", "text/css"},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(http.MethodGet, testCase.Path, nil)
+		req.Header.Set("Accept-Encoding", testCase.AcceptEncoding)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Body.String() != testCase.Body {
+			t.Errorf("%s: %s %q: expected %q got %q", t.Name(), testCase.Path, testCase.AcceptEncoding, testCase.Body, w.Body.String())
+		}
+
+		if w.Header().Get("Content-Encoding") != testCase.Encoding {
+			t.Errorf("%s: %s %q: expected encoding %q got %q", t.Name(), testCase.Path, testCase.AcceptEncoding, testCase.Encoding, w.Header().Get("Content-Encoding"))
+		}
+
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), testCase.ContentType) {
+			t.Errorf("%s: %s %q: expected content type %q got %q", t.Name(), testCase.Path, testCase.AcceptEncoding, testCase.ContentType, w.Header().Get("Content-Type"))
+		}
+
+		if w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%s: %s %q: expected Vary Accept-Encoding got %q", t.Name(), testCase.Path, testCase.AcceptEncoding, w.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestFileSystem_Caching(t *testing.T) {
+	r := New(false, nil, nil)
+	r.RegisterFileSystem(FS(fstest.MapFS{
+		"app.3f2a9c1b.js":      {Data: []byte("fingerprinted")},
+		"app.js":               {Data: []byte("plain")},
+		"invoice-12345678.pdf": {Data: []byte("invoice")},
+		"report-20240101.pdf":  {Data: []byte("report")},
+	}), "/assets")
+
+	cases := []struct {
+		Path         string
+		CacheControl string
+	}{
+		{"/assets/app.3f2a9c1b.js", "public, max-age=31536000, immutable"},
+		{"/assets/app.js", "no-cache"},
+		{"/assets/invoice-12345678.pdf", "no-cache"},
+		{"/assets/report-20240101.pdf", "no-cache"},
+	}
+
+	for _, testCase := range cases {
+		req, _ := http.NewRequest(http.MethodGet, testCase.Path, nil)
+		w := httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Header().Get("Cache-Control") != testCase.CacheControl {
+			t.Errorf("%s: %s: expected %q got %q", t.Name(), testCase.Path, testCase.CacheControl, w.Header().Get("Cache-Control"))
+		}
+
+		etag := w.Header().Get("ETag")
+		if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) || len(etag) < 3 {
+			t.Fatalf("%s: %s: expected a strong ETag got %q", t.Name(), testCase.Path, etag)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, testCase.Path, nil)
+		req.Header.Set("If-None-Match", etag)
+		w = httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotModified {
+			t.Errorf("%s: %s: expected %d got %d", t.Name(), testCase.Path, http.StatusNotModified, w.Code)
+		}
+
+		req, _ = http.NewRequest(http.MethodGet, testCase.Path, nil)
+		req.Header.Set("If-None-Match", `"stale"`)
+		w = httptest.NewRecorder()
+
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: %s: expected %d got %d", t.Name(), testCase.Path, http.StatusOK, w.Code)
+		}
+	}
+}
@@ -2,76 +2,296 @@ package router
 
 import (
 	"net/http"
-	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 )
 
-// node contains the struct that holds information related to single node
+// node contains the struct that holds information related to single node of the radix tree. Static text shared by
+// routes is stored once in the node where the routes diverge, named parameters and catch-alls get a node of their
+// own and always start right after a '/'
 type node struct {
-	// children contains the children for existing node
-	children []*node
-	// component contains the name of the individual node
+	// prefix contains the static text matched by the node, it is empty for parameter nodes
+	prefix string
+	// indices contains the first byte of the prefix of every static child, in the same order as static
+	indices string
+	// static contains the children matching static text
+	static []*node
+	// params contains the named parameter children, the constrained ones come before the unconstrained one
+	params []*node
+	// catchAll contains the catch-all child, it is only tried when no static or named child leads to a handler
+	catchAll *node
+	// component contains the component a parameter node was registered with, like :id<int> or *path
 	component string
-	// isNamedParam tells if a param is a named parameter or not
-	isNamedParam bool
-	// methods contains a map to http method and a handler for it
+	// paramName is the name the value of a named or catch-all parameter is stored under
+	paramName string
+	// constraint is the expression the value of a named parameter has to match, nil when it is unconstrained
+	constraint *regexp.Regexp
+	// methods contains a map to http method and a handler for it, a handler for anyMethod serves the methods
+	// without a handler of their own
 	methods map[string]http.HandlerFunc
 }
 
-// addNode adds a path to existing tree by splitting the path on "/"
-// each split is considered a node and handler is added to the last node
-func (n *node) addNode(method, path string, handler http.HandlerFunc) {
+// anyMethod is the key of the handler of a node that serves every method
+const anyMethod = "*"
+
+// newNode creates a static node matching prefix
+func newNode(prefix string) *node {
+	return &node{prefix: prefix, methods: make(map[string]http.HandlerFunc)}
+}
+
+// addNode adds a path to existing tree, the static text between the parameters of the path is inserted into the
+// radix tree and the handler is added to the last node. Catch-all parameters must be named unless unnamedCatchAll is
+// set, which is only done for the catch-all of a mounted handler
+func (n *node) addNode(method, path string, handler http.HandlerFunc, unnamedCatchAll bool) {
 	components := strings.Split(path, "/")[1:]
-	count := len(components)
 
-	for count != 0 {
-		aNode, component := n.traverse(components, nil)
+	aNode := n
+	static := ""
+	for idx, component := range components {
+		static += "/"
+
+		if len(component) == 0 || (component[0] != ':' && component[0] != '*') {
+			static += component
+			continue
+		}
+
+		isCatchAll := component[0] == '*'
+
+		if isCatchAll && idx != len(components)-1 {
+			panic("catch-all parameter must be the last component in path '" + path + "'")
+		}
+
+		if isCatchAll && len(component) < 2 && !unnamedCatchAll {
+			panic("catch-all parameter must be named in path '" + path + "'")
+		}
+
+		if isCatchAll && strings.ContainsRune(component, '<') {
+			panic("catch-all parameter can not be constrained in path '" + path + "'")
+		}
+
+		aNode = aNode.insertStatic(static)
+		aNode = aNode.insertParam(component, path)
+		static = ""
+	}
 
-		// Means node already exists, so we are updating one
-		if aNode.component == component && count == 1 {
-			// TODO add stub to create a panic when registration happens in the type
-			// TODO /foo/bar/:hello & /foo/bar/boom
-			aNode.methods[method] = handler
-			return
+	if static != "" {
+		aNode = aNode.insertStatic(static)
+	}
+
+	aNode.methods[method] = handler
+}
+
+// insertStatic returns the node matching the static text below n, creating it if it does not exist. When the text
+// only shares part of the prefix of an existing child, the child is split at the end of the shared part
+func (n *node) insertStatic(text string) *node {
+	for len(text) > 0 {
+		idx := strings.IndexByte(n.indices, text[0])
+		if idx == -1 {
+			child := newNode(text)
+			n.indices += string(text[0])
+			n.static = append(n.static, child)
+			return child
 		}
 
-		newNode := node{component: component, isNamedParam: false, methods: make(map[string]http.HandlerFunc)}
+		child := n.static[idx]
 
-		// Check if it is a named param
-		if len(component) > 0 && component[0] == ':' {
-			newNode.isNamedParam = true
+		shared := 0
+		for shared < len(text) && shared < len(child.prefix) && text[shared] == child.prefix[shared] {
+			shared++
 		}
 
-		// this is the last component of the url resource, so it gets the handler
-		if count == 1 {
-			newNode.methods[method] = handler
+		if shared < len(child.prefix) {
+			split := newNode(child.prefix[:shared])
+			split.indices = string(child.prefix[shared])
+			split.static = []*node{child}
+
+			child.prefix = child.prefix[shared:]
+			n.static[idx] = split
+			child = split
 		}
 
-		// Adds child to the current node
-		aNode.children = append(aNode.children, &newNode)
-		count--
+		n = child
+		text = text[shared:]
 	}
+
+	return n
 }
 
-// traverse, traverses through the root node from the path components and returns
-// the node and the component name
-func (n *node) traverse(components []string, params url.Values) (*node, string) {
-	component := components[0]
-	if len(n.children) > 0 { // no children, then bail out.
-		for _, child := range n.children {
-			if component == child.component || child.isNamedParam {
-				if child.isNamedParam && params != nil {
-					params.Add(child.component[1:], component)
+// insertParam returns the named or catch-all child of n registered with the component, creating it if it does not
+// exist. Named children are kept ordered so the constrained ones are tried before the unconstrained one
+func (n *node) insertParam(component, path string) *node {
+	paramName, constraint := parseParam(component)
+
+	if component[0] == '*' {
+		// A component can only have one catch-all, as otherwise the name the value is stored under would depend on
+		// the order the routes were registered in
+		if n.catchAll != nil && n.catchAll.component != component {
+			panic("'" + component + "' in path '" + path + "' conflicts with existing wildcard '" + n.catchAll.component + "'")
+		}
+
+		if n.catchAll == nil {
+			n.catchAll = &node{component: component, paramName: paramName, methods: make(map[string]http.HandlerFunc)}
+		}
+
+		return n.catchAll
+	}
+
+	for _, child := range n.params {
+		if child.component == component {
+			return child
+		}
+	}
+
+	newParam := &node{
+		component:  component,
+		paramName:  paramName,
+		constraint: compileConstraint(constraint, path),
+		methods:    make(map[string]http.HandlerFunc),
+	}
+
+	// A component can only have one named parameter per constraint, for the same reason as the catch-all
+	for _, child := range n.params {
+		sameConstraint := newParam.constraint == child.constraint
+		if newParam.constraint != nil && child.constraint != nil {
+			sameConstraint = newParam.constraint.String() == child.constraint.String()
+		}
+
+		if sameConstraint {
+			panic("'" + component + "' in path '" + path + "' conflicts with existing wildcard '" + child.component + "'")
+		}
+	}
+
+	// Constrained params are tried in the order they were registered in, before the unconstrained one
+	idx := len(n.params)
+	if newParam.constraint != nil && idx > 0 && n.params[idx-1].constraint == nil {
+		idx--
+	}
+
+	n.params = append(n.params, nil)
+	copy(n.params[idx+1:], n.params[idx:])
+	n.params[idx] = newParam
+
+	return newParam
+}
+
+// allowed returns the value of the Allow header for the node, HEAD is allowed when there is a GET handler and
+// OPTIONS when it is answered automatically
+func (n *node) allowed(withOptions bool) string {
+	methods := make([]string, 0, len(n.methods)+2)
+	for method := range n.methods {
+		methods = append(methods, method)
+	}
+
+	if _, ok := n.methods[http.MethodGet]; ok {
+		if _, ok := n.methods[http.MethodHead]; !ok {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+
+	if _, ok := n.methods[http.MethodOptions]; !ok && withOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+
+	sort.Strings(methods)
+
+	return strings.Join(methods, ", ")
+}
+
+// matches tells if the segment can be captured by the named parameter node
+func (n *node) matches(segment string) bool {
+	return segment != "" && (n.constraint == nil || n.constraint.MatchString(segment))
+}
+
+// search looks up the rest of the path below the node and returns the node holding the handlers, or nil when no
+// node matches. Static children are tried first, then the named parameters and lastly the catch-all, when a branch
+// does not lead to a handler the next one is tried instead. Params are appended to params without allocating when
+// it has enough capacity
+func (n *node) search(path string, params *Params) *node {
+	if path == "" && len(n.methods) > 0 {
+		return n
+	}
+
+	if path != "" {
+		if idx := strings.IndexByte(n.indices, path[0]); idx != -1 {
+			child := n.static[idx]
+			if strings.HasPrefix(path, child.prefix) {
+				if found := child.search(path[len(child.prefix):], params); found != nil {
+					return found
+				}
+			}
+		}
+
+		if len(n.params) > 0 {
+			end := strings.IndexByte(path, '/')
+			if end == -1 {
+				end = len(path)
+			}
+
+			for _, child := range n.params {
+				if !child.matches(path[:end]) {
+					continue
+				}
+
+				count := len(*params)
+				*params = append(*params, Param{Key: child.paramName, Value: path[:end]})
+
+				if found := child.search(path[end:], params); found != nil {
+					return found
 				}
-				next := components[1:]
-				if len(next) > 0 {
-					return child.traverse(next, params)
-				} else {
-					return child, component
+
+				// Drop the params of the abandoned branch before trying the next one
+				*params = (*params)[:count]
+			}
+		}
+	}
+
+	// A catch-all captures the rest of the path including the slashes in it, an unnamed one matches it without
+	// storing it
+	if n.catchAll != nil {
+		if n.catchAll.paramName != "" {
+			*params = append(*params, Param{Key: n.catchAll.paramName, Value: path})
+		}
+		return n.catchAll
+	}
+
+	return nil
+}
+
+// searchCaseInsensitive looks up the rest of the path below the node like search but compares static text without
+// regard to case. It returns fixed with the path appended in the casing it was registered with, or nil when no node
+// matches
+func (n *node) searchCaseInsensitive(path string, fixed []byte) []byte {
+	if path == "" && len(n.methods) > 0 {
+		return fixed
+	}
+
+	if path != "" {
+		for _, child := range n.static {
+			if len(path) >= len(child.prefix) && strings.EqualFold(path[:len(child.prefix)], child.prefix) {
+				if found := child.searchCaseInsensitive(path[len(child.prefix):], append(fixed, child.prefix...)); found != nil {
+					return found
+				}
+			}
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end == -1 {
+			end = len(path)
+		}
+
+		for _, child := range n.params {
+			if child.matches(path[:end]) {
+				if found := child.searchCaseInsensitive(path[end:], append(fixed, path[:end]...)); found != nil {
+					return found
 				}
 			}
 		}
 	}
 
-	return n, component
+	if n.catchAll != nil {
+		return append(fixed, path...)
+	}
+
+	return nil
 }
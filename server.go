@@ -0,0 +1,216 @@
+package cyclops
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultDrainTimeout is the time a Server stopped by a signal gives the open connections to finish
+const DefaultDrainTimeout = 30 * time.Second
+
+// Server is an http server which shuts down gracefully, when it receives one of its Signals it stops accepting
+// connections, waits up to DrainTimeout for the open ones to finish and runs the OnShutdown hooks. The zero value is
+// ready to use, NewServer is a shorthand for setting the Address and Handler
+type Server struct {
+	// Address is the TCP address the server listens on, like :8080
+	Address string
+	// Listen creates the listener the server accepts connections on instead of listening on the Address, like a
+	// UnixSocket or a SystemdSocket
+	Listen ListenFunc
+	// Handler handles the requests, usually a *router.Router
+	Handler http.Handler
+	// DrainTimeout is the time the open connections are given to finish when the server is stopped by a signal,
+	// DefaultDrainTimeout when it is zero
+	DrainTimeout time.Duration
+	// Signals are the signals stopping the server, SIGINT and SIGTERM when it is empty
+	Signals []os.Signal
+	// IgnoreSignals stops the server from handling signals, for applications handling them themselves and calling
+	// Shutdown
+	IgnoreSignals bool
+	// Options configures the timeouts and limits of the server
+	Options Options
+	// TLSConfig configures the TLS connections of StartTLS, like the certificates of a Certificates
+	TLSConfig *tls.Config
+	// H2C serves HTTP/2 without TLS next to HTTP/1.1 on Start, for clients which know the server supports it like
+	// services behind a mesh. StartTLS always serves HTTP/2
+	H2C bool
+
+	initOnce     sync.Once
+	server       *http.Server
+	onStart      []func() error
+	onShutdown   []func(ctx context.Context) error
+	shutdownOnce sync.Once
+	shutdownErr  error
+	done         chan struct{}
+}
+
+// NewServer creates a Server listening on address with the DefaultDrainTimeout
+func NewServer(address string, handler http.Handler) *Server {
+	return &Server{
+		Address:      address,
+		Handler:      handler,
+		DrainTimeout: DefaultDrainTimeout,
+	}
+}
+
+// OnStart registers a hook which runs once the server listens, before it accepts requests. Hooks run in the order
+// they were registered, when one fails the server is not started
+func (s *Server) OnStart(hook func() error) {
+	s.onStart = append(s.onStart, hook)
+}
+
+// OnShutdown registers a hook which runs once the open connections are drained, like closing the session store.
+// Hooks run in the reverse order they were registered, with the context passed to Shutdown
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, hook)
+}
+
+// Start listens on the Address, or with Listen when it is set, and serves requests until the server receives one of
+// its Signals or Shutdown is called, it returns once the server is shut down. The error is nil when the server was
+// shut down gracefully
+func (s *Server) Start() error {
+	return s.start(func(listener net.Listener) error {
+		return s.server.Serve(listener)
+	})
+}
+
+// StartTLS is like Start but serves HTTPS with the provided TLS cert and key files, they can be empty when the
+// TLSConfig provides the certificates
+func (s *Server) StartTLS(certFile, keyFile string) error {
+	return s.start(func(listener net.Listener) error {
+		return s.server.ServeTLS(listener, certFile, keyFile)
+	})
+}
+
+// start listens with Listen or on the Address, runs the OnStart hooks and serves requests with serve until the
+// server is shut down
+func (s *Server) start(serve func(listener net.Listener) error) error {
+	s.init()
+	s.configure()
+
+	listen := s.Listen
+	if listen == nil {
+		listen = TCPSocket(s.Address)
+	}
+
+	listener, err := listen()
+	if err != nil {
+		return err
+	}
+
+	// A nil channel never receives, so the server only stops on Shutdown when it ignores signals
+	var signals chan os.Signal
+	if !s.IgnoreSignals {
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, s.signals()...)
+		defer signal.Stop(signals)
+	}
+
+	for _, hook := range s.onStart {
+		if err := hook(); err != nil {
+			_ = listener.Close()
+			return err
+		}
+	}
+
+	if !s.Options.HideBanner {
+		fmt.Print(banner)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		if err == http.ErrServerClosed {
+			// Shutdown was called, wait for it to drain the connections and run the hooks
+			<-s.done
+			return s.shutdownErr
+		}
+
+		_ = s.drain()
+		return err
+	case <-signals:
+		return s.drain()
+	}
+}
+
+// init creates the http.Server and the channel closed on shutdown, so a Server does not have to be created by
+// NewServer
+func (s *Server) init() {
+	s.initOnce.Do(func() {
+		s.server = &http.Server{}
+		s.done = make(chan struct{})
+	})
+}
+
+// configure sets the address, handler and options of the server on the underlying http.Server
+func (s *Server) configure() {
+	s.server.Addr = s.Address
+	s.server.Handler = s.Handler
+	s.server.TLSConfig = s.TLSConfig
+	s.Options.apply(s.server)
+
+	s.server.Protocols = nil
+	if s.H2C {
+		s.server.Protocols = new(http.Protocols)
+		s.server.Protocols.SetHTTP1(true)
+		s.server.Protocols.SetHTTP2(true)
+		s.server.Protocols.SetUnencryptedHTTP2(true)
+	}
+}
+
+// drain shuts the server down, giving the open connections DrainTimeout to finish
+func (s *Server) drain() error {
+	timeout := s.DrainTimeout
+	if timeout == 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return s.Shutdown(ctx)
+}
+
+// Shutdown stops accepting connections, waits for the open ones to finish or ctx to be done and runs the OnShutdown
+// hooks. It returns the error of the server shutting down or else of the first failing hook, calling it again
+// returns the same error
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.init()
+
+	s.shutdownOnce.Do(func() {
+		err := s.server.Shutdown(ctx)
+
+		for idx := len(s.onShutdown) - 1; idx >= 0; idx-- {
+			if hookErr := s.onShutdown[idx](ctx); hookErr != nil && err == nil {
+				err = hookErr
+			}
+		}
+
+		s.shutdownErr = err
+		close(s.done)
+	})
+
+	<-s.done
+	return s.shutdownErr
+}
+
+// signals returns the signals stopping the server
+func (s *Server) signals() []os.Signal {
+	if len(s.Signals) == 0 {
+		return []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	}
+
+	return s.Signals
+}
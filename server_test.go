@@ -0,0 +1,258 @@
+package cyclops
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// freeAddress returns a local address nothing listens on
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+
+	server := NewServer(freeAddress(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+		_, _ = io.WriteString(w, "drained")
+	}))
+	server.Options.HideBanner = true
+
+	var events []string
+	started := make(chan struct{})
+	server.OnStart(func() error {
+		events = append(events, "start")
+		close(started)
+		return nil
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		events = append(events, "close sessions")
+		return nil
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		events = append(events, "flush logs")
+		return nil
+	})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+	<-started
+
+	responded := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + server.Address)
+		if err != nil {
+			responded <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		responded <- string(body)
+	}()
+	<-received
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(context.Background())
+	}()
+
+	// Give Shutdown the time to close the listener before the request in flight finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if body := <-responded; body != "drained" {
+		t.Errorf("%s: expected the request in flight to be drained got %q", t.Name(), body)
+	}
+
+	if err := <-shutdown; err != nil {
+		t.Errorf("%s: unexpected shutdown error: %s", t.Name(), err.Error())
+	}
+
+	if err := <-stopped; err != nil {
+		t.Errorf("%s: unexpected start error: %s", t.Name(), err.Error())
+	}
+
+	expected := []string{"start", "flush logs", "close sessions"}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("%s: expected %v got %v", t.Name(), expected, events)
+	}
+}
+
+func TestServer_Signal(t *testing.T) {
+	server := NewServer(freeAddress(t), http.NotFoundHandler())
+	server.Options.HideBanner = true
+	server.Signals = []os.Signal{syscall.SIGUSR1}
+
+	started := make(chan struct{})
+	server.OnStart(func() error {
+		close(started)
+		return nil
+	})
+
+	closed := false
+	server.OnShutdown(func(ctx context.Context) error {
+		closed = true
+		return nil
+	})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start()
+	}()
+	<-started
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("%s: unexpected start error: %s", t.Name(), err.Error())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s: server did not stop on the signal", t.Name())
+	}
+
+	if !closed {
+		t.Errorf("%s: expected the shutdown hook to run", t.Name())
+	}
+}
+
+func TestServer_OnStartError(t *testing.T) {
+	hookErr := errors.New("redis unavailable")
+
+	server := NewServer(freeAddress(t), http.NotFoundHandler())
+	server.Options.HideBanner = true
+	server.OnStart(func() error {
+		return hookErr
+	})
+
+	if err := server.Start(); err != hookErr {
+		t.Errorf("%s: expected %v got %v", t.Name(), hookErr, err)
+	}
+}
+
+func TestOptions_apply(t *testing.T) {
+	cases := []struct {
+		Options  Options
+		Expected Options
+	}{
+		{
+			Options{},
+			Options{
+				ReadTimeout:       DefaultReadTimeout,
+				ReadHeaderTimeout: DefaultReadHeaderTimeout,
+				WriteTimeout:      DefaultWriteTimeout,
+				IdleTimeout:       DefaultIdleTimeout,
+				MaxHeaderBytes:    DefaultMaxHeaderBytes,
+			},
+		},
+		{
+			Options{ReadTimeout: time.Second, WriteTimeout: -1, MaxHeaderBytes: 4096},
+			Options{
+				ReadTimeout:       time.Second,
+				ReadHeaderTimeout: DefaultReadHeaderTimeout,
+				IdleTimeout:       DefaultIdleTimeout,
+				MaxHeaderBytes:    4096,
+			},
+		},
+	}
+
+	for _, testCase := range cases {
+		server := &http.Server{}
+		testCase.Options.apply(server)
+
+		applied := Options{
+			ReadTimeout:       server.ReadTimeout,
+			ReadHeaderTimeout: server.ReadHeaderTimeout,
+			WriteTimeout:      server.WriteTimeout,
+			IdleTimeout:       server.IdleTimeout,
+			MaxHeaderBytes:    server.MaxHeaderBytes,
+		}
+
+		if applied != testCase.Expected {
+			t.Errorf("%s: %+v: expected %+v got %+v", t.Name(), testCase.Options, testCase.Expected, applied)
+		}
+	}
+}
+
+func TestServer_ZeroValue(t *testing.T) {
+	// Shutdown does not need the server to be created by NewServer or started
+	stopped := &Server{Options: Options{HideBanner: true}}
+	if err := stopped.Shutdown(context.Background()); err != nil {
+		t.Errorf("%s: unexpected shutdown error: %s", t.Name(), err.Error())
+	}
+
+	server := &Server{
+		Address: freeAddress(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "zero")
+		}),
+		Signals:       []os.Signal{syscall.SIGUSR2},
+		IgnoreSignals: true,
+		Options:       Options{HideBanner: true},
+	}
+
+	started := make(chan struct{})
+	server.OnStart(func() error {
+		close(started)
+		return nil
+	})
+
+	result := make(chan error, 1)
+	go func() {
+		result <- server.Start()
+	}()
+	<-started
+
+	// The application handles the signal itself, the server keeps serving
+	own := make(chan os.Signal, 1)
+	signal.Notify(own, syscall.SIGUSR2)
+	defer signal.Stop(own)
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGUSR2); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	<-own
+
+	resp, err := http.Get("http://" + server.Address)
+	if err != nil {
+		t.Fatalf("%s: expected the server to keep serving: %s", t.Name(), err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if string(body) != "zero" {
+		t.Errorf("%s: expected %q got %q", t.Name(), "zero", string(body))
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("%s: unexpected shutdown error: %s", t.Name(), err.Error())
+	}
+
+	if err := <-result; err != nil {
+		t.Errorf("%s: unexpected start error: %s", t.Name(), err.Error())
+	}
+}
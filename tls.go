@@ -0,0 +1,310 @@
+package cyclops
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Certificates holds the certificates of a TLS server and selects the one matching the server name the client asks
+// for with SNI. Certificates loaded from files are reloaded when the files change, so renewed certificates are served
+// without a restart
+type Certificates struct {
+	mu sync.RWMutex
+	// entries contains the certificates in the order they were added, the first one is served to clients which do
+	// not send a known server name
+	entries []*certificateEntry
+	// names contains the certificates by the lower cased names they are valid for, wildcard names like
+	// *.example.com included
+	names map[string]*tls.Certificate
+}
+
+// certificateEntry is a certificate of Certificates with the files it was loaded from, if any
+type certificateEntry struct {
+	certificate *tls.Certificate
+	certFile    string
+	keyFile     string
+	modTime     time.Time
+}
+
+// NewCertificates creates an empty set of certificates
+func NewCertificates() *Certificates {
+	return &Certificates{names: make(map[string]*tls.Certificate)}
+}
+
+// Add adds an in-memory certificate, like one created by SelfSignedCertificate
+func (c *Certificates) Add(certificate tls.Certificate) error {
+	return c.add(&certificateEntry{certificate: &certificate})
+}
+
+// AddFiles adds the certificate of a PEM encoded cert and key file pair, it is reloaded by Reload when one of the
+// files changed
+func (c *Certificates) AddFiles(certFile, keyFile string) error {
+	certificate, modTime, err := loadCertificate(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+
+	return c.add(&certificateEntry{certificate: certificate, certFile: certFile, keyFile: keyFile, modTime: modTime})
+}
+
+// add adds the certificate of the entry and indexes it by the names it is valid for
+func (c *Certificates) add(entry *certificateEntry) error {
+	if err := parseLeaf(entry.certificate); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = append(c.entries, entry)
+	c.index()
+
+	return nil
+}
+
+// index rebuilds the names of the certificates, a name valid for several certificates selects the one added first
+func (c *Certificates) index() {
+	c.names = make(map[string]*tls.Certificate)
+
+	for _, entry := range c.entries {
+		leaf := entry.certificate.Leaf
+
+		names := append([]string{leaf.Subject.CommonName}, leaf.DNSNames...)
+		for _, ip := range leaf.IPAddresses {
+			names = append(names, ip.String())
+		}
+
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, ok := c.names[name]; !ok && name != "" {
+				c.names[name] = entry.certificate
+			}
+		}
+	}
+}
+
+// GetCertificate returns the certificate valid for the server name of the client, a certificate for *.example.com is
+// valid for api.example.com. The first certificate is returned when no certificate is valid for the server name, so
+// it can be used as tls.Config.GetCertificate
+func (c *Certificates) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if len(c.entries) == 0 {
+		return nil, errors.New("cyclops: no certificates")
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	if certificate, ok := c.names[name]; ok {
+		return certificate, nil
+	}
+
+	if idx := strings.IndexByte(name, '.'); idx != -1 {
+		if certificate, ok := c.names["*"+name[idx:]]; ok {
+			return certificate, nil
+		}
+	}
+
+	return c.entries[0].certificate, nil
+}
+
+// Reload reloads the certificates whose files changed since they were loaded. A certificate which fails to load
+// keeps being served and the first error is returned
+func (c *Certificates) Reload() error {
+	c.mu.RLock()
+	entries := append([]*certificateEntry(nil), c.entries...)
+	c.mu.RUnlock()
+
+	var firstErr error
+	reloaded := make(map[*certificateEntry]*certificateEntry)
+
+	for _, entry := range entries {
+		if entry.certFile == "" {
+			continue
+		}
+
+		modTime, err := latestModTime(entry.certFile, entry.keyFile)
+		if err == nil && !modTime.After(entry.modTime) {
+			continue
+		}
+
+		certificate, modTime, err := loadCertificate(entry.certFile, entry.keyFile)
+		if err == nil {
+			err = parseLeaf(certificate)
+		}
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		reloaded[entry] = &certificateEntry{
+			certificate: certificate,
+			certFile:    entry.certFile,
+			keyFile:     entry.keyFile,
+			modTime:     modTime,
+		}
+	}
+
+	if len(reloaded) > 0 {
+		c.mu.Lock()
+		for idx, entry := range c.entries {
+			if replacement, ok := reloaded[entry]; ok {
+				c.entries[idx] = replacement
+			}
+		}
+		c.index()
+		c.mu.Unlock()
+	}
+
+	return firstErr
+}
+
+// Watch reloads the certificates every interval until ctx is done, errors are logged and the previous certificates
+// keep being served
+func (c *Certificates) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.Reload(); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}()
+}
+
+// TLSConfig returns a tls.Config serving the certificates
+func (c *Certificates) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
+
+// SelfSignedCertificate generates a self-signed certificate valid for a year for the hosts, which can be names or IP
+// addresses. It is meant for local development, as clients do not trust it
+func SelfSignedCertificate(hosts ...string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	notBefore := time.Now().Add(-time.Minute)
+
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"Cyclops Self-Signed"}},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	if len(hosts) > 0 {
+		template.Subject.CommonName = hosts[0]
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// loadCertificate loads a cert and key file pair and returns it with the latest modification time of the files
+func loadCertificate(certFile, keyFile string) (*tls.Certificate, time.Time, error) {
+	// The modification time is read first, so a change while loading is picked up by the next reload
+	modTime, err := latestModTime(certFile, keyFile)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return &certificate, modTime, nil
+}
+
+// latestModTime returns the latest modification time of the files
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+
+	for _, file := range files {
+		stat, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if stat.ModTime().After(latest) {
+			latest = stat.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// parseLeaf parses the leaf of the certificate unless it is already parsed
+func parseLeaf(certificate *tls.Certificate) error {
+	if certificate.Leaf != nil {
+		return nil
+	}
+
+	if len(certificate.Certificate) == 0 {
+		return errors.New("cyclops: certificate is empty")
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return err
+	}
+
+	certificate.Leaf = leaf
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package cyclops
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertificates_GetCertificate(t *testing.T) {
+	certificates := NewCertificates()
+
+	for _, hosts := range [][]string{{"example.com"}, {"*.api.example.com"}, {"127.0.0.1"}} {
+		certificate, err := SelfSignedCertificate(hosts...)
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		if err := certificates.Add(certificate); err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+	}
+
+	cases := []struct {
+		ServerName string
+		Expected   string
+	}{
+		{"example.com", "example.com"},
+		{"EXAMPLE.com.", "example.com"},
+		{"eu.api.example.com", "*.api.example.com"},
+		{"127.0.0.1", "127.0.0.1"},
+		{"unknown.org", "example.com"},
+		{"", "example.com"},
+	}
+
+	for _, testCase := range cases {
+		certificate, err := certificates.GetCertificate(&tls.ClientHelloInfo{ServerName: testCase.ServerName})
+		if err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+
+		if certificate.Leaf.Subject.CommonName != testCase.Expected {
+			t.Errorf("%s: %q: expected %q got %q", t.Name(), testCase.ServerName, testCase.Expected, certificate.Leaf.Subject.CommonName)
+		}
+	}
+}
+
+// writeCertificate writes a self-signed certificate for host as PEM encoded cert and key files
+func writeCertificate(t *testing.T, certFile, keyFile, host string) {
+	certificate, err := SelfSignedCertificate(host)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	key, err := x509.MarshalPKCS8PrivateKey(certificate.PrivateKey)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]})
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+}
+
+func TestCertificates_Reload(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	writeCertificate(t, certFile, keyFile, "old.example.com")
+
+	certificates := NewCertificates()
+	if err := certificates.AddFiles(certFile, keyFile); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	writeCertificate(t, certFile, keyFile, "new.example.com")
+
+	later := time.Now().Add(time.Minute)
+	for _, file := range []string{certFile, keyFile} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatalf("%s: %s", t.Name(), err.Error())
+		}
+	}
+
+	if err := certificates.Reload(); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	certificate, _ := certificates.GetCertificate(&tls.ClientHelloInfo{ServerName: "new.example.com"})
+	if certificate.Leaf.Subject.CommonName != "new.example.com" {
+		t.Errorf("%s: expected the reloaded certificate got %q", t.Name(), certificate.Leaf.Subject.CommonName)
+	}
+
+	// A broken file keeps the loaded certificate
+	if err := os.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	evenLater := later.Add(time.Minute)
+	if err := os.Chtimes(keyFile, evenLater, evenLater); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	if err := certificates.Reload(); err == nil {
+		t.Errorf("%s: expected an error reloading a broken key", t.Name())
+	}
+
+	certificate, _ = certificates.GetCertificate(&tls.ClientHelloInfo{ServerName: "new.example.com"})
+	if certificate.Leaf.Subject.CommonName != "new.example.com" {
+		t.Errorf("%s: expected the loaded certificate to be kept got %q", t.Name(), certificate.Leaf.Subject.CommonName)
+	}
+}
+
+func TestServer_StartTLSConfig(t *testing.T) {
+	certificate, err := SelfSignedCertificate("localhost")
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	certificates := NewCertificates()
+	if err := certificates.Add(certificate); err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+
+	server := NewServer(freeAddress(t), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.ServerName)
+	}))
+	server.Options.HideBanner = true
+	server.TLSConfig = certificates.TLSConfig()
+
+	started := make(chan struct{})
+	server.OnStart(func() error {
+		close(started)
+		return nil
+	})
+
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.StartTLS("", "")
+	}()
+	<-started
+
+	roots := x509.NewCertPool()
+	roots.AddCert(certificate.Leaf)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"},
+	}}
+
+	resp, err := client.Get("https://" + server.Address)
+	if err != nil {
+		t.Fatalf("%s: %s", t.Name(), err.Error())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if string(body) != "localhost" {
+		t.Errorf("%s: expected %q got %q", t.Name(), "localhost", string(body))
+	}
+
+	if err := server.Shutdown(context.Background()); err != nil {
+		t.Errorf("%s: unexpected shutdown error: %s", t.Name(), err.Error())
+	}
+
+	if err := <-stopped; err != nil {
+		t.Errorf("%s: unexpected start error: %s", t.Name(), err.Error())
+	}
+}